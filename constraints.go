@@ -0,0 +1,414 @@
+package testparcer
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// constraint встроенное ограничение значения поля
+type constraint struct {
+	// prepare проверяет при разборе тэга, что правило подходит полю типа t
+	prepare func(t reflect.Type, r *rule) error
+	// check возвращает ошибку, если значение v не подходит
+	check func(v reflect.Value, r rule) error
+}
+
+var constraints = map[string]constraint{
+	"min":     {prepare: prepareBound, check: checkMin},
+	"max":     {prepare: prepareBound, check: checkMax},
+	"len":     {prepare: prepareLen, check: checkLen},
+	"oneof":   {prepare: prepareOneOf, check: checkOneOf},
+	"pattern": {prepare: preparePattern, check: checkPattern},
+}
+
+// checkConstraints проверяет значение поля sf встроенными правилами и правилами WithRule.
+// Пустой указатель не проверяется
+func (w *walker) checkConstraints(n *node, sf reflect.StructField, name string, f reflect.Value, rs rules) {
+	for f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return
+		}
+		f = f.Elem()
+	}
+	for _, r := range rs {
+		var err error
+		if c, ok := constraints[r.name]; ok {
+			err = c.check(f, r)
+		} else if fn, ok := w.p.customRules[r.name]; ok {
+			_, path := stepPaths(append(w.path, step{field: sf.Name, token: name}))
+			err = fn(f.Interface(), path, r.param)
+		}
+		if err != nil {
+			fe := w.fieldError(n, sf, name, Kind(r.name))
+			fe.Value = f.Interface()
+			fe.Err = err
+			w.invalidate(fe)
+			if w.done() {
+				return
+			}
+		}
+	}
+}
+
+// isLenKind сообщает, что min и max для типа сравниваются с длиной, а не со значением
+func isLenKind(k reflect.Kind) bool {
+	return k == reflect.String || k == reflect.Slice || k == reflect.Map || k == reflect.Array
+}
+
+// length возвращает длину строки в символах или число элементов
+func length(v reflect.Value) int {
+	if v.Kind() == reflect.String {
+		return utf8.RuneCountInString(v.String())
+	}
+	return v.Len()
+}
+
+// compareParam сравнивает значение или длину v с параметром правила: -1, 0 или 1
+func compareParam(v reflect.Value, param string) (int, error) {
+	switch k := v.Kind(); {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Int(), int64(d)), nil
+	case k >= reflect.Int && k <= reflect.Int64:
+		i, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Int(), i), nil
+	case k >= reflect.Uint && k <= reflect.Uintptr:
+		u, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Uint(), u), nil
+	case k == reflect.Float32 || k == reflect.Float64:
+		f, err := strconv.ParseFloat(param, v.Type().Bits())
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Float(), f), nil
+	case isLenKind(k):
+		l, err := strconv.Atoi(param)
+		if err != nil {
+			return 0, err
+		}
+		if l < 0 {
+			return 0, fmt.Errorf("negative length %d", l)
+		}
+		return cmp.Compare(length(v), l), nil
+	}
+	return 0, fmt.Errorf("not supported for %v", v.Type())
+}
+
+func prepareBound(t reflect.Type, r *rule) error {
+	_, err := compareParam(reflect.Zero(t), r.param)
+	return err
+}
+
+func checkMin(v reflect.Value, r rule) error {
+	if c, _ := compareParam(v, r.param); c >= 0 {
+		return nil
+	}
+	if isLenKind(v.Kind()) {
+		return fmt.Errorf("length %d is less than %v", length(v), r.param)
+	}
+	return fmt.Errorf("%v is less than %v", v.Interface(), r.param)
+}
+
+func checkMax(v reflect.Value, r rule) error {
+	if c, _ := compareParam(v, r.param); c <= 0 {
+		return nil
+	}
+	if isLenKind(v.Kind()) {
+		return fmt.Errorf("length %d is greater than %v", length(v), r.param)
+	}
+	return fmt.Errorf("%v is greater than %v", v.Interface(), r.param)
+}
+
+func prepareLen(t reflect.Type, r *rule) error {
+	if !isLenKind(t.Kind()) {
+		return fmt.Errorf("not supported for %v", t)
+	}
+	return prepareBound(t, r)
+}
+
+func checkLen(v reflect.Value, r rule) error {
+	if c, _ := compareParam(v, r.param); c == 0 {
+		return nil
+	}
+	return fmt.Errorf("length %d is not %v", length(v), r.param)
+}
+
+// prepareOneOf проверяет, что каждое из значений через пробел подходит типу поля
+func prepareOneOf(t reflect.Type, r *rule) error {
+	values := strings.Fields(r.param)
+	if len(values) == 0 {
+		return fmt.Errorf("no values")
+	}
+	if t.Kind() == reflect.String {
+		return nil
+	}
+	if k := t.Kind(); k < reflect.Int || k > reflect.Uintptr || t == durationType {
+		return fmt.Errorf("not supported for %v", t)
+	}
+	for _, s := range values {
+		if _, err := compareParam(reflect.Zero(t), s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkOneOf(v reflect.Value, r rule) error {
+	for _, s := range strings.Fields(r.param) {
+		if v.Kind() == reflect.String && v.String() == s {
+			return nil
+		}
+		if v.Kind() != reflect.String {
+			if c, _ := compareParam(v, s); c == 0 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%v is not one of [%v]", v.Interface(), r.param)
+}
+
+func preparePattern(t reflect.Type, r *rule) error {
+	if t.Kind() != reflect.String {
+		return fmt.Errorf("not supported for %v", t)
+	}
+	re, err := regexp.Compile(r.param)
+	if err != nil {
+		return err
+	}
+	r.re = re
+	return nil
+}
+
+func checkPattern(v reflect.Value, r rule) error {
+	if r.re.MatchString(v.String()) {
+		return nil
+	}
+	return fmt.Errorf("%q does not match %v", v.String(), r.param)
+}
+
+// requiredBy сообщает, обязательно ли поле с правилами rs в объекте n. Для условных правил
+// возвращает условие, которое выполнилось. Соседние поля ищутся по ключу json среди fields,
+// keys — ключи полей в n, как их возвращает fieldKeys
+func (w *walker) requiredBy(rs rules, n *node, fields *structFields, keys []string) (bool, error) {
+	if rs.has("required") {
+		return true, nil
+	}
+
+	// set возвращает значение соседнего поля, если оно задано и не null
+	set := func(name string) *node {
+		at := n.field(keys[fields.byName[name]])
+		if at == nil || at.kind == nodeNull {
+			return nil
+		}
+		return at
+	}
+	for _, r := range rs {
+		args := strings.Fields(r.param)
+		switch r.name {
+		case "required_if":
+			match := true
+			for i := 0; i+1 < len(args) && match; i += 2 {
+				match = w.equals(set(args[i]), args[i+1])
+			}
+			if match {
+				return true, fmt.Errorf("required if %v", r.param)
+			}
+		case "required_with":
+			for _, name := range args {
+				if set(name) != nil {
+					return true, fmt.Errorf("required with %q", name)
+				}
+			}
+		case "required_without":
+			for _, name := range args {
+				if set(name) == nil {
+					return true, fmt.Errorf("required without %q", name)
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// equals сравнивает значение в json с текстом: строки — без кавычек, остальное — как записано
+func (w *walker) equals(n *node, s string) bool {
+	if n == nil || w.data == nil {
+		return false
+	}
+	raw := w.data[n.start:n.end]
+	if n.kind == nodeString {
+		str, err := unquote(raw)
+		return err == nil && str == s
+	}
+	return string(raw) == s
+}
+
+// fieldComparisons сравнения со значением другого поля: проверка результата cmp и текст ошибки
+var fieldComparisons = map[string]struct {
+	ok   func(c int) bool
+	text string
+}{
+	"eqfield":  {func(c int) bool { return c == 0 }, "equal to"},
+	"gtfield":  {func(c int) bool { return c > 0 }, "greater than"},
+	"gtefield": {func(c int) bool { return c >= 0 }, "greater than or equal to"},
+	"ltfield":  {func(c int) bool { return c < 0 }, "less than"},
+	"ltefield": {func(c int) bool { return c <= 0 }, "less than or equal to"},
+}
+
+// isOrdered сообщает, что значения типа t можно сравнивать на больше и меньше
+func isOrdered(t reflect.Type) bool {
+	switch k := t.Kind(); {
+	case t == timeType, k == reflect.String, k == reflect.Float32, k == reflect.Float64:
+		return true
+	case k >= reflect.Int && k <= reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// compareValues сравнивает значения одного типа: -1, 0 или 1
+func compareValues(a, b reflect.Value) int {
+	switch k := a.Kind(); {
+	case a.Type() == timeType:
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	case k == reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case k >= reflect.Int && k <= reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case k >= reflect.Uint && k <= reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case k == reflect.Float32 || k == reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	}
+	if reflect.DeepEqual(a.Interface(), b.Interface()) {
+		return 0
+	}
+	return 1
+}
+
+// checkComparison проверяет при разборе тэга, что поле типа t можно сравнить с соседним полем типа other
+func checkComparison(r rule, t, other reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for other.Kind() == reflect.Ptr {
+		other = other.Elem()
+	}
+	if t != other {
+		return fmt.Errorf("option %q: field %q has type %v, want %v", r.name, r.param, other, t)
+	}
+	if r.name != "eqfield" && !isOrdered(t) {
+		return fmt.Errorf("option %q: not supported for %v", r.name, t)
+	}
+	return nil
+}
+
+// compareFields сравнивает i-е поле структуры v со значениями других полей из правил
+// eqfield, gtfield, gtefield, ltfield и ltefield. Значения берутся после того, как
+// выставлены значения по умолчанию. Пустые указатели не сравниваются
+func (w *walker) compareFields(v reflect.Value, n *node, i int, f reflect.Value, fields *structFields, keys []string) {
+	rs, _ := w.p.cachedRules(v.Type())
+	for _, r := range rs[i] {
+		c, ok := fieldComparisons[r.name]
+		if !ok {
+			continue
+		}
+		other, field, path, err := w.otherField(v, fields, r.param)
+		if err != nil {
+			fe := w.fieldError(n, v.Type().FieldByIndex(fields.list[i].index), keys[i], KindTag)
+			fe.Err = err
+			w.fail(fe)
+			return
+		}
+		a, b := reflect.Indirect(f), reflect.Indirect(other)
+		if !a.IsValid() || !b.IsValid() {
+			continue
+		}
+		if a.Type() != b.Type() {
+			fe := w.fieldError(n, v.Type().FieldByIndex(fields.list[i].index), keys[i], KindTag)
+			fe.Err = fmt.Errorf("option %q: field %q has type %v, want %v", r.name, r.param, b.Type(), a.Type())
+			w.fail(fe)
+			return
+		}
+		if c.ok(compareValues(a, b)) {
+			continue
+		}
+		fe := w.fieldError(n, v.Type().FieldByIndex(fields.list[i].index), keys[i], Kind(r.name))
+		fe.Value = a.Interface()
+		fe.Err = fmt.Errorf(`%v is not %v field "%v" (path "%v") %v`, a.Interface(), c.text, field, path, b.Interface())
+		w.fail(fe)
+		if w.done() {
+			return
+		}
+	}
+}
+
+// otherField находит поле из параметра правила сравнения: соседнее поле по ключу json
+// или поле по пути от корня цели, например /limits/max. Если цель — срез, путь отсчитывается
+// от его элемента, как если бы элемент разбирался отдельно. Возвращает путь до поля в go и JSON Pointer
+func (w *walker) otherField(v reflect.Value, fields *structFields, param string) (reflect.Value, string, string, error) {
+	if !strings.HasPrefix(param, "/") {
+		i := fields.byName[param]
+		f, _ := fieldValue(v, fields.list[i].index)
+		field, path := stepPaths(append(w.path, step{field: fields.list[i].goName, token: param}))
+		return f, field, path, nil
+	}
+
+	cur := w.top
+	steps := append([]step(nil), w.topPath...)
+	for _, token := range strings.Split(param[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		for cur.Kind() == reflect.Ptr && !cur.IsNil() {
+			cur = cur.Elem()
+		}
+		switch cur.Kind() {
+		case reflect.Struct:
+			fs := cachedFields(cur.Type(), w.p.fieldTag)
+			j, ok := fs.byName[token]
+			if !ok {
+				return reflect.Value{}, "", "", fmt.Errorf("unknown field %q in %q", token, param)
+			}
+			next, ok := fieldValue(cur, fs.list[j].index)
+			if !ok {
+				next = reflect.Zero(cur.Type().FieldByIndex(fs.list[j].index).Type)
+			}
+			cur = next
+			steps = append(steps, step{field: fs.list[j].goName, token: token})
+		case reflect.Slice, reflect.Array:
+			j, err := strconv.Atoi(token)
+			if err != nil || j < 0 || j >= cur.Len() {
+				return reflect.Value{}, "", "", fmt.Errorf("no element %q in %q", token, param)
+			}
+			cur = cur.Index(j)
+			steps = append(steps, step{field: "[" + token + "]", token: token})
+		case reflect.Map:
+			if cur.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, "", "", fmt.Errorf("unsupported map key in %q", param)
+			}
+			cur = cur.MapIndex(reflect.ValueOf(token).Convert(cur.Type().Key()))
+			if !cur.IsValid() {
+				return reflect.Value{}, "", "", fmt.Errorf("no element %q in %q", token, param)
+			}
+			steps = append(steps, step{field: "[" + token + "]", token: token})
+		default:
+			return reflect.Value{}, "", "", fmt.Errorf("cannot resolve %q in %q", token, param)
+		}
+	}
+	field, path := stepPaths(steps)
+	return cur, field, path, nil
+}
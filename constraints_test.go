@@ -0,0 +1,389 @@
+package testparcer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConstraintsStruct struct {
+	Port    int               `json:"port" parcer:"min=1,max=65535"`
+	Ratio   float64           `json:"ratio" parcer:"min=0,max=1"`
+	Share   float32           `json:"share" parcer:"max=0.1"`
+	Timeout time.Duration     `json:"timeout" parcer:"min=1s,max=1m"`
+	Name    string            `json:"name" parcer:"min=2,max=5"`
+	Code    string            `json:"code" parcer:"len=3"`
+	Tags    []string          `json:"tags" parcer:"max=2"`
+	Labels  map[string]string `json:"labels" parcer:"min=1"`
+	Mode    string            `json:"mode" parcer:"oneof=dev prod"`
+	Level   *int              `json:"level" parcer:"oneof=1 2 3"`
+	Host    string            `json:"host" parcer:"pattern=^[a-z]{1,10}(\\.[a-z]{1,10})*$"`
+	Retries uint              `json:"retries,required" parcer:"max=10"`
+}
+
+func TestConstraints(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Kind
+	}{
+		{"valid", `{"port": 80, "ratio": 0.5, "timeout": "30s", "name": "ab", "code": "abc", "tags": ["a"],
+			"labels": {"a": "b"}, "mode": "dev", "level": 2, "host": "example.com", "retries": 3}`, ""},
+		{"absent values are not checked", `{"retries": 1}`, ""},
+		{"null pointer", `{"level": null, "retries": 1}`, ""},
+		{"min number", `{"port": 0, "retries": 1}`, KindMin},
+		{"max number", `{"port": 70000, "retries": 1}`, KindMax},
+		{"max float", `{"ratio": 1.5, "retries": 1}`, KindMax},
+		{"min duration", `{"timeout": "10ms", "retries": 1}`, KindMin},
+		{"max uint", `{"retries": 11}`, KindMax},
+		{"min string length", `{"name": "a", "retries": 1}`, KindMin},
+		{"max string length in runes", `{"name": "привет", "retries": 1}`, KindMax},
+		{"len", `{"code": "abcd", "retries": 1}`, KindLen},
+		{"max slice length", `{"tags": ["a", "b", "c"], "retries": 1}`, KindMax},
+		{"min map length", `{"labels": {}, "retries": 1}`, KindMin},
+		{"oneof string", `{"mode": "test", "retries": 1}`, KindOneOf},
+		{"oneof pointer", `{"level": 4, "retries": 1}`, KindOneOf},
+		{"pattern", `{"host": "Example.com", "retries": 1}`, KindPattern},
+		{"float32 at max", `{"share": 0.1, "retries": 1}`, ""},
+		{"float32 above max", `{"share": 0.10001, "retries": 1}`, KindMax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), &testConstraintsStruct{})
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ParceBytes() error = %v", err)
+				}
+				return
+			}
+
+			var fe *FieldError
+			if !errors.Is(err, ErrorWhileValidating) || !errors.As(err, &fe) || fe.Kind != tt.want {
+				t.Errorf("ParceBytes() error = %v, want kind %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstraints_allErrors(t *testing.T) {
+	err := ParceBytes([]byte(`{"port": 0, "name": "a"}`), &testConstraintsStruct{}, WithAllErrors())
+	if !errors.Is(err, ErrorWhileChekingRequired) || !errors.Is(err, ErrorWhileValidating) {
+		t.Fatalf("ParceBytes() error = %v", err)
+	}
+
+	var kinds []Kind
+	for _, fe := range FieldErrors(err) {
+		kinds = append(kinds, fe.Kind)
+	}
+	if len(kinds) != 3 || kinds[0] != KindRequired || kinds[1] != KindMin || kinds[2] != KindMin {
+		t.Errorf("FieldErrors() kinds = %v", kinds)
+	}
+}
+
+func TestConstraints_invalidTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		target interface{}
+	}{
+		{"bad number", &struct {
+			Port int `parcer:"min=one"`
+		}{}},
+		{"bad duration", &struct {
+			Timeout time.Duration `parcer:"max=10"`
+		}{}},
+		{"len of number", &struct {
+			Port int `parcer:"len=1"`
+		}{}},
+		{"min of bool", &struct {
+			On bool `parcer:"min=1"`
+		}{}},
+		{"empty oneof", &struct {
+			Mode string `parcer:"oneof="`
+		}{}},
+		{"bad oneof number", &struct {
+			Level int `parcer:"oneof=1 two"`
+		}{}},
+		{"pattern of number", &struct {
+			Port int `parcer:"pattern=^1$"`
+		}{}},
+		{"bad pattern", &struct {
+			Host string `parcer:"pattern=("`
+		}{}},
+		{"option after pattern", &struct {
+			Host string `parcer:"pattern=^[a-z]+$,max=3"`
+		}{}},
+		{"json option after pattern", &struct {
+			Host string `json:"host,pattern=^[a-z]+$,omitempty"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(`{}`), tt.target)
+			if !errors.Is(err, ErrorInvalidTag) {
+				t.Errorf("ParceBytes() error = %v, want %v", err, ErrorInvalidTag)
+			}
+		})
+	}
+}
+
+type testRuleTopic struct {
+	Name   string `json:"name" parcer:"topic"`
+	Prefix string `json:"prefix" parcer:"prefix=app."`
+}
+
+type testRuleStruct struct {
+	Topic  testRuleTopic             `json:"topic"`
+	Ptr    *testRuleTopic            `json:"ptr"`
+	Topics []testRuleTopic           `json:"topics"`
+	ByName map[string]*testRuleTopic `json:"by-name"`
+}
+
+func TestWithRule(t *testing.T) {
+	var paths []string
+	topic := func(value interface{}, path, param string) error {
+		paths = append(paths, path)
+		if s := value.(string); s == "" || strings.ContainsAny(s, " /") {
+			return fmt.Errorf("invalid topic name %q", s)
+		}
+		return nil
+	}
+	prefix := func(value interface{}, path, param string) error {
+		if !strings.HasPrefix(value.(string), param) {
+			return fmt.Errorf("want prefix %q", param)
+		}
+		return nil
+	}
+	opts := []Option{WithRule("topic", topic), WithRule("prefix", prefix), WithAllErrors()}
+
+	data := `{"topic": {"name": "a"}, "ptr": {"name": "b b"}, "topics": [{"name": "c", "prefix": "app.c"}, {"prefix": "x"}],
+		"by-name": {"d": {"name": "d/d"}}}`
+	err := ParceBytes([]byte(data), &testRuleStruct{}, opts...)
+	if !errors.Is(err, ErrorWhileValidating) {
+		t.Fatalf("ParceBytes() error = %v, want %v", err, ErrorWhileValidating)
+	}
+
+	var got []string
+	for _, fe := range FieldErrors(err) {
+		got = append(got, fmt.Sprintf("%v %v %v", fe.Kind, fe.Field, fe.Path))
+	}
+	want := []string{
+		"topic Ptr.Name /ptr/name",
+		"prefix Topics[1].Prefix /topics/1/prefix",
+		"topic ByName[d].Name /by-name/d/name",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FieldErrors() = %q, want %q", got, want)
+	}
+	wantPaths := []string{"/topic/name", "/ptr/name", "/topics/0/name", "/by-name/d/name"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("rule paths = %q, want %q", paths, wantPaths)
+	}
+}
+
+func TestWithRule_unknown(t *testing.T) {
+	err := ParceBytes([]byte(`{}`), &testRuleStruct{})
+	if !errors.Is(err, ErrorInvalidTag) {
+		t.Errorf("ParceBytes() error = %v, want %v", err, ErrorInvalidTag)
+	}
+}
+
+func TestWithRule_reserved(t *testing.T) {
+	fn := func(interface{}, string, string) error { return nil }
+	for _, name := range []string{"min", "required_if", "string", "omitempty", "omitzero", "", "a=b"} {
+		t.Run(name, func(t *testing.T) {
+			target := &struct {
+				Name string `json:"name,omitempty"`
+			}{}
+			err := ParceBytes([]byte(`{"name": "a"}`), target, WithRule(name, fn))
+			if !errors.Is(err, ErrorInvalidOption) {
+				t.Errorf("ParceBytes() error = %v, want %v", err, ErrorInvalidOption)
+			}
+		})
+	}
+}
+
+type testTLS struct {
+	Enabled  bool   `json:"enabled"`
+	Mode     string `json:"mode"`
+	CertFile string `json:"cert_file" parcer:"required_if=enabled true"`
+	KeyFile  string `json:"key_file" parcer:"required_with=cert_file"`
+	CAFile   string `json:"ca_file" parcer:"required_if=enabled true mode mutual"`
+	Password string `json:"password" parcer:"required_without=token"`
+	Token    string `json:"token"`
+}
+
+func TestRequiredConditions(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"disabled", `{"token": "t"}`, ""},
+		{"explicitly disabled", `{"enabled": false, "token": "t"}`, ""},
+		{"required_if", `{"enabled": true, "token": "t"}`, "CertFile"},
+		{"required_if set", `{"enabled": true, "cert_file": "c", "key_file": "k", "token": "t"}`, ""},
+		{"required_if all pairs", `{"enabled": true, "mode": "mutual", "cert_file": "c", "key_file": "k", "token": "t"}`, "CAFile"},
+		{"required_if string", `{"mode": "mutual", "token": "t"}`, ""},
+		{"required_with", `{"cert_file": "c", "token": "t"}`, "KeyFile"},
+		{"required_without", `{}`, "Password"},
+		{"required_without set", `{"password": "p"}`, ""},
+		{"required_without null", `{"token": null}`, "Password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), &testTLS{})
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ParceBytes() error = %v", err)
+				}
+				return
+			}
+
+			var fe *FieldError
+			if !errors.Is(err, ErrorWhileChekingRequired) || !errors.As(err, &fe) ||
+				fe.Kind != KindRequired || fe.Field != tt.want || fe.Err == nil {
+				t.Errorf("ParceBytes() error = %v, want missing %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequiredConditions_invalidTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		target interface{}
+	}{
+		{"unknown field", &struct {
+			Cert string `json:"cert" parcer:"required_with=key"`
+		}{}},
+		{"odd pairs", &struct {
+			On   bool   `json:"on"`
+			Cert string `json:"cert" parcer:"required_if=on"`
+		}{}},
+		{"go name", &struct {
+			On   bool   `json:"on"`
+			Cert string `json:"cert" parcer:"required_without=On"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(`{}`), tt.target)
+			if !errors.Is(err, ErrorInvalidTag) {
+				t.Errorf("ParceBytes() error = %v, want %v", err, ErrorInvalidTag)
+			}
+		})
+	}
+}
+
+type testPool struct {
+	MinConns int        `json:"min_conns" default:"1" parcer:"ltefield=max_conns"`
+	MaxConns int        `json:"max_conns" default:"10"`
+	Start    *time.Time `json:"start"`
+	End      *time.Time `json:"end" parcer:"gtfield=start"`
+	Password string     `json:"password"`
+	Confirm  string     `json:"confirm" parcer:"eqfield=password"`
+	Limit    *int       `json:"limit" parcer:"ltfield=/limits/max"`
+}
+
+type testPools struct {
+	Limits struct {
+		Max int `json:"max" default:"100"`
+	} `json:"limits"`
+	Pool testPool `json:"pool"`
+}
+
+func TestFieldComparisons(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		want  Kind
+		field string
+		other string
+	}{
+		{"defaults", `{}`, "", "", ""},
+		{"valid", `{"pool": {"min_conns": 5, "max_conns": 5, "start": "2024-01-01T00:00:00Z",
+			"end": "2024-01-02T00:00:00Z", "password": "p", "confirm": "p", "limit": 99}}`, "", "", ""},
+		{"against default", `{"pool": {"min_conns": 20}}`, KindLteField, "Pool.MinConns", `"Pool.MaxConns" (path "/pool/max_conns")`},
+		{"time", `{"pool": {"start": "2024-01-02T00:00:00Z", "end": "2024-01-01T00:00:00Z"}}`, KindGtField, "Pool.End", `"Pool.Start"`},
+		{"eqfield", `{"pool": {"password": "p", "confirm": "q"}}`, KindEqField, "Pool.Confirm", `"Pool.Password"`},
+		{"root path", `{"limits": {"max": 10}, "pool": {"limit": 10}}`, KindLtField, "Pool.Limit", `"Limits.Max" (path "/limits/max")`},
+		{"root path default", `{"pool": {"limit": 100}}`, KindLtField, "Pool.Limit", `"Limits.Max"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), &testPools{})
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ParceBytes() error = %v", err)
+				}
+				return
+			}
+
+			var fe *FieldError
+			if !errors.Is(err, ErrorWhileValidating) || !errors.As(err, &fe) || fe.Kind != tt.want || fe.Field != tt.field {
+				t.Fatalf("ParceBytes() error = %v, want %v of %v", err, tt.want, tt.field)
+			}
+			if !strings.Contains(err.Error(), tt.other) {
+				t.Errorf("ParceBytes() error = %v, want other field %v", err, tt.other)
+			}
+		})
+	}
+}
+
+func TestFieldComparisons_sliceTarget(t *testing.T) {
+	// путь от корня отсчитывается от элемента среза, как при разборе одного элемента
+	data := `[{"limits": {"max": 10}, "pool": {"limit": 5}}, {"limits": {"max": 10}, "pool": {"limit": 10}}]`
+	err := ParceBytes([]byte(data), &[]testPools{})
+
+	var fe *FieldError
+	if !errors.Is(err, ErrorWhileValidating) || !errors.As(err, &fe) || fe.Kind != KindLtField || fe.Field != "[1].Pool.Limit" {
+		t.Fatalf("ParceBytes() error = %v, want %v of [1].Pool.Limit", err, KindLtField)
+	}
+	if want := `"[1].Limits.Max" (path "/1/limits/max")`; !strings.Contains(err.Error(), want) {
+		t.Errorf("ParceBytes() error = %v, want other field %v", err, want)
+	}
+}
+
+func TestFieldComparisons_invalidTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		target interface{}
+	}{
+		{"unknown field", &struct {
+			Min int `json:"min" parcer:"ltfield=max"`
+		}{}},
+		{"different types", &struct {
+			Min int     `json:"min" parcer:"ltfield=max"`
+			Max float64 `json:"max"`
+		}{}},
+		{"unordered", &struct {
+			A []int `json:"a" parcer:"gtfield=b"`
+			B []int `json:"b"`
+		}{}},
+		{"empty", &struct {
+			A int `json:"a" parcer:"eqfield="`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(`{}`), tt.target)
+			if !errors.Is(err, ErrorInvalidTag) {
+				t.Errorf("ParceBytes() error = %v, want %v", err, ErrorInvalidTag)
+			}
+		})
+	}
+}
+
+func TestFieldComparisons_unknownPath(t *testing.T) {
+	target := &struct {
+		Min int `json:"min" parcer:"ltfield=/limits/max"`
+	}{}
+	err := ParceBytes([]byte(`{"min": 1}`), target)
+	var fe *FieldError
+	if !errors.Is(err, ErrorWhileValidating) || !errors.As(err, &fe) || fe.Kind != KindTag {
+		t.Errorf("ParceBytes() error = %v, want %v", err, KindTag)
+	}
+}
@@ -0,0 +1,741 @@
+package testparcer
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// layoutTag тэг с форматом time.Time для time.Parse, по умолчанию RFC3339
+const layoutTag = "layout"
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	numberType          = reflect.TypeOf(json.Number(""))
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// decodeJSON разбирает data один раз: строит дерево node и по нему заполняет target.
+// Дерево возвращается для проверки обязательных полей и значений по умолчанию.
+// Ошибки разбора возвращаются как *DecodeError с позицией в файле name.
+// Неизвестные ключи в режиме WarnUnknownFields передаются в warn
+func decodeJSON(name string, data []byte, target interface{}, p *Parser, warn func(*FieldError)) (*node, error) {
+	n, err := parseTree(data)
+	if err != nil {
+		var offset int
+		var se *syntaxError
+		if errors.As(err, &se) {
+			offset = se.offset
+		}
+		return nil, &DecodeError{Pos: position(name, data, offset), Err: err}
+	}
+
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, &json.InvalidUnmarshalError{Type: reflect.TypeOf(target)}
+	}
+
+	d := decodeState{p: p, name: name, data: data, warn: warn}
+	err = d.value(rv.Elem(), n)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// decodeState раскладывает дерево node по значению цели так же, как это делает encoding/json.
+// Значения, которые умеют разбирать себя сами, и редкие типы отдаются в json.Unmarshal
+type decodeState struct {
+	name  string
+	data  []byte
+	path  []string // ключи от корня, как в json.UnmarshalTypeError.Field
+	steps []step   // путь от корня для FieldError
+	strct string
+	p     *Parser
+	warn  func(*FieldError) // получает неизвестные ключи в режиме WarnUnknownFields
+}
+
+func (d *decodeState) raw(n *node) []byte {
+	return d.data[n.start:n.end]
+}
+
+// errorAt привязывает ошибку к смещению offset в исходных данных
+func (d *decodeState) errorAt(offset int, err error) error {
+	return &DecodeError{Pos: position(d.name, d.data, offset), Err: err}
+}
+
+func (d *decodeState) typeError(v reflect.Value, n *node, what string) error {
+	return d.errorAt(n.start, &json.UnmarshalTypeError{
+		Value:  what,
+		Type:   v.Type(),
+		Offset: int64(n.end),
+		Struct: d.strct,
+		Field:  strings.Join(d.path, "."),
+	})
+}
+
+func (d *decodeState) delegate(v reflect.Value, n *node) error {
+	err := json.Unmarshal(d.raw(n), v.Addr().Interface())
+	if err != nil {
+		return d.errorAt(n.start, err)
+	}
+	return nil
+}
+
+func (d *decodeState) value(v reflect.Value, n *node) error {
+	if v.Kind() == reflect.Ptr {
+		if n.kind == nodeNull {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.value(v.Elem(), n)
+	}
+
+	if isUnmarshaler(v.Type()) {
+		return d.delegate(v, n)
+	}
+
+	if v.Type() == durationType && n.kind == nodeString {
+		s, err := d.unquote(n)
+		if err != nil {
+			return d.errorAt(n.start, err)
+		}
+		dur, err := time.ParseDuration(s)
+		if err != nil {
+			return d.errorAt(n.start, err)
+		}
+		v.SetInt(int64(dur))
+		return nil
+	}
+
+	if n.kind == nodeNull {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if n.kind != nodeObject {
+			return d.typeError(v, n, n.kindName())
+		}
+		return d.object(v, n)
+	case reflect.Map:
+		if !isMapKey(v.Type().Key()) {
+			return d.delegate(v, n)
+		}
+		if n.kind != nodeObject {
+			return d.typeError(v, n, n.kindName())
+		}
+		return d.mapping(v, n)
+	case reflect.Slice:
+		if n.kind == nodeString {
+			return d.delegate(v, n)
+		}
+		if n.kind != nodeArray {
+			return d.typeError(v, n, n.kindName())
+		}
+		return d.slice(v, n)
+	case reflect.Array:
+		if n.kind != nodeArray {
+			return d.typeError(v, n, n.kindName())
+		}
+		if len(n.members) != v.Len() {
+			return d.lengthError(v, n)
+		}
+		for i := 0; i < v.Len(); i++ {
+			err := d.elem(v.Index(i), n.members[i].val, i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		if v.Type() == numberType {
+			return d.delegate(v, n)
+		}
+		if n.kind != nodeString {
+			return d.typeError(v, n, n.kindName())
+		}
+		s, err := d.unquote(n)
+		if err != nil {
+			return d.errorAt(n.start, err)
+		}
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		if n.kind != nodeBool {
+			return d.typeError(v, n, n.kindName())
+		}
+		v.SetBool(d.data[n.start] == 't')
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.kind != nodeNumber {
+			return d.typeError(v, n, n.kindName())
+		}
+		i, err := strconv.ParseInt(string(d.raw(n)), 10, 64)
+		if err != nil || v.OverflowInt(i) {
+			return d.typeError(v, n, "number "+string(d.raw(n)))
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n.kind != nodeNumber {
+			return d.typeError(v, n, n.kindName())
+		}
+		u, err := strconv.ParseUint(string(d.raw(n)), 10, 64)
+		if err != nil || v.OverflowUint(u) {
+			return d.typeError(v, n, "number "+string(d.raw(n)))
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if n.kind != nodeNumber {
+			return d.typeError(v, n, n.kindName())
+		}
+		f, err := strconv.ParseFloat(string(d.raw(n)), v.Type().Bits())
+		if err != nil || v.OverflowFloat(f) {
+			return d.typeError(v, n, "number "+string(d.raw(n)))
+		}
+		v.SetFloat(f)
+		return nil
+	}
+
+	return d.delegate(v, n)
+}
+
+func (d *decodeState) object(v reflect.Value, n *node) error {
+	fields := cachedFields(v.Type(), d.p.fieldTag)
+	strct := d.strct
+	d.strct = v.Type().Name()
+	defer func() { d.strct = strct }()
+
+	for _, m := range n.members {
+		f := fields.byKey(m.key)
+		if f == nil {
+			err := d.unknownField(m)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		fv, err := fieldByIndex(v, f.index)
+		if err != nil {
+			return d.errorAt(m.start, err)
+		}
+
+		d.path = append(d.path, f.name)
+		d.steps = append(d.steps, step{field: f.goName, token: m.key})
+		if f.quoted {
+			err = d.quoted(fv, m.val)
+		} else if f.layout != "" {
+			err = d.time(fv, m.val, f.layout)
+		} else {
+			err = d.value(fv, m.val)
+		}
+		d.path = d.path[:len(d.path)-1]
+		d.steps = d.steps[:len(d.steps)-1]
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unknownField обрабатывает ключ m, которому нет поля в структуре
+func (d *decodeState) unknownField(m member) error {
+	switch d.p.unknownFields {
+	case IgnoreUnknownFields:
+		return nil
+	case WarnUnknownFields:
+		if d.warn != nil {
+			field, path := stepPaths(append(d.steps, step{token: m.key}))
+			d.warn(&FieldError{
+				Field: field,
+				Path:  path,
+				Tag:   m.key,
+				Kind:  KindUnknown,
+				Pos:   position(d.name, d.data, m.start),
+			})
+		}
+		return nil
+	}
+	return d.errorAt(m.start, fmt.Errorf("json: unknown field %q", m.key))
+}
+
+func (d *decodeState) mapping(v reflect.Value, n *node) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, len(n.members)))
+	}
+	for _, m := range n.members {
+		elem := reflect.New(t.Elem()).Elem()
+		d.path = append(d.path, m.key)
+		d.steps = append(d.steps, step{field: "[" + m.key + "]", token: m.key})
+		err := d.value(elem, m.val)
+		d.path = d.path[:len(d.path)-1]
+		d.steps = d.steps[:len(d.steps)-1]
+		if err != nil {
+			return err
+		}
+		key, err := d.mapKey(t.Key(), m)
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// isMapKey сообщает, умеет ли декодер разбирать ключи отображения типа t сам:
+// строки, целые числа и encoding.TextUnmarshaler, как encoding/json
+func isMapKey(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// mapKey преобразует ключ объекта m в ключ отображения типа t
+func (d *decodeState) mapKey(t reflect.Type, m member) (reflect.Value, error) {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		k := reflect.New(t)
+		if err := k.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(m.key)); err != nil {
+			return reflect.Value{}, d.errorAt(m.start, err)
+		}
+		return k.Elem(), nil
+	}
+
+	k := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		k.SetString(m.key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(m.key, 10, 64)
+		if err != nil || k.OverflowInt(i) {
+			return reflect.Value{}, d.keyError(t, m)
+		}
+		k.SetInt(i)
+	default:
+		u, err := strconv.ParseUint(m.key, 10, 64)
+		if err != nil || k.OverflowUint(u) {
+			return reflect.Value{}, d.keyError(t, m)
+		}
+		k.SetUint(u)
+	}
+	return k, nil
+}
+
+func (d *decodeState) keyError(t reflect.Type, m member) error {
+	return d.errorAt(m.start, &json.UnmarshalTypeError{
+		Value:  "number " + m.key,
+		Type:   t,
+		Offset: int64(m.start + 1),
+		Struct: d.strct,
+		Field:  strings.Join(append(d.path, m.key), "."),
+	})
+}
+
+func (d *decodeState) slice(v reflect.Value, n *node) error {
+	l := len(n.members)
+	if v.Cap() < l {
+		nv := reflect.MakeSlice(v.Type(), l, l)
+		reflect.Copy(nv, v)
+		v.Set(nv)
+	} else {
+		old := v.Len()
+		v.SetLen(l)
+		for i := old; i < l; i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	}
+	if l == 0 && v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	for i, m := range n.members {
+		err := d.elem(v.Index(i), m.val, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decodeState) elem(v reflect.Value, n *node, i int) error {
+	key := strconv.Itoa(i)
+	d.steps = append(d.steps, step{field: "[" + key + "]", token: key})
+	err := d.value(v, n)
+	d.steps = d.steps[:len(d.steps)-1]
+	return err
+}
+
+// lengthError сообщает, что в массиве json другое число элементов, чем в массиве go.
+// Ошибка указывает на первый лишний или недостающий элемент
+func (d *decodeState) lengthError(v reflect.Value, n *node) error {
+	i := v.Len()
+	offset := n.end - 1
+	if len(n.members) < i {
+		i = len(n.members)
+	} else {
+		offset = n.members[i].start
+	}
+	key := strconv.Itoa(i)
+	field, path := stepPaths(append(d.steps, step{field: "[" + key + "]", token: key}))
+	return d.errorAt(offset, &FieldError{
+		Field: field,
+		Path:  path,
+		Tag:   key,
+		Kind:  KindLength,
+		Value: len(n.members),
+		Err:   fmt.Errorf("json array has %d elements, want %d", len(n.members), v.Len()),
+	})
+}
+
+// quoted разбирает поле с опцией ",string", значение которого записано строкой
+func (d *decodeState) quoted(v reflect.Value, n *node) error {
+	if n.kind == nodeNull {
+		return nil
+	}
+	if n.kind != nodeString {
+		return d.errorAt(n.start, fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", v.Type()))
+	}
+	s, err := d.unquote(n)
+	if err != nil {
+		return d.errorAt(n.start, err)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	err = json.Unmarshal([]byte(s), v.Addr().Interface())
+	if err != nil {
+		return d.errorAt(n.start, fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", s, v.Type()))
+	}
+	return nil
+}
+
+// time разбирает поле time.Time или список time.Time, формат которых задан тэгом layout
+func (d *decodeState) time(v reflect.Value, n *node, layout string) error {
+	if n.kind == nodeNull {
+		return d.value(v, n)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && n.kind == nodeArray {
+		// формат относится и к элементам списков
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(n.members), len(n.members)))
+		} else if len(n.members) != v.Len() {
+			return d.lengthError(v, n)
+		}
+		for i, m := range n.members {
+			if err := d.time(v.Index(i), m.val, layout); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if v.Type() != timeType || n.kind != nodeString {
+		return d.value(v, n)
+	}
+
+	s, err := d.unquote(n)
+	if err != nil {
+		return d.errorAt(n.start, err)
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return d.errorAt(n.start, err)
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func (d *decodeState) unquote(n *node) (string, error) {
+	return unquote(d.raw(n))
+}
+
+var unmarshalerCache sync.Map
+
+// isUnmarshaler сообщает, разбирает ли тип себя сам через json.Unmarshaler или encoding.TextUnmarshaler
+func isUnmarshaler(t reflect.Type) bool {
+	if ok, found := unmarshalerCache.Load(t); found {
+		return ok.(bool)
+	}
+	pt := reflect.PtrTo(t)
+	ok := pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType)
+	unmarshalerCache.Store(t, ok)
+	return ok
+}
+
+// fieldByIndex возвращает вложенное поле, создавая встроенные указатели на структуры
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// fieldValue возвращает вложенное поле, не создавая встроенные указатели.
+// ok ложно, если один из них пустой
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// field поле структуры в том виде, в каком его видит encoding/json
+type field struct {
+	name   string
+	goName string
+	tagged bool
+	quoted bool
+	layout string // формат time.Time из тэга layout
+	index  []int
+}
+
+type structFields struct {
+	list   []field
+	byName map[string]int
+}
+
+// byKey ищет поле по ключу json
+func (fs *structFields) byKey(key string) *field {
+	if i := fs.lookup(key); i >= 0 {
+		return &fs.list[i]
+	}
+	return nil
+}
+
+// lookup возвращает индекс поля для ключа json: сначала точное совпадение, затем
+// без учета регистра. Если поля нет, возвращает -1
+func (fs *structFields) lookup(key string) int {
+	if i, ok := fs.byName[key]; ok {
+		return i
+	}
+	for i := range fs.list {
+		if strings.EqualFold(fs.list[i].name, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+var fieldCache sync.Map
+
+type fieldCacheKey struct {
+	typ reflect.Type
+	tag string
+}
+
+// cachedFields возвращает поля типа t, имена которых берутся из тэга tag
+func cachedFields(t reflect.Type, tag string) *structFields {
+	key := fieldCacheKey{typ: t, tag: tag}
+	if fs, ok := fieldCache.Load(key); ok {
+		return fs.(*structFields)
+	}
+	fs, _ := fieldCache.LoadOrStore(key, typeFields(t, tag))
+	return fs.(*structFields)
+}
+
+// typeFields повторяет правила encoding/json: поля встроенных структур поднимаются наверх,
+// при совпадении имен побеждает менее вложенное поле, а среди равных — помеченное тэгом
+func typeFields(t reflect.Type, tagName string) *structFields {
+	type queued struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var all []field
+	visited := map[reflect.Type]bool{}
+	next := []queued{{typ: t}}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		level := map[reflect.Type]bool{}
+		for _, q := range current {
+			if visited[q.typ] {
+				continue
+			}
+			level[q.typ] = true
+
+			for i := 0; i < q.typ.NumField(); i++ {
+				sf := q.typ.Field(i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				tag := sf.Tag.Get(tagName)
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseJSONTag(tag)
+				if !isValidTag(name) {
+					name = ""
+				}
+
+				index := make([]int, len(q.index)+1)
+				copy(index, q.index)
+				index[len(q.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					quoted := false
+					if hasOption(opts, "string") {
+						switch ft.Kind() {
+						case reflect.Bool,
+							reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+							reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+							reflect.Float32, reflect.Float64,
+							reflect.String:
+							quoted = true
+						}
+					}
+					f := field{name: name, goName: sf.Name, tagged: name != "", quoted: quoted, layout: sf.Tag.Get(layoutTag), index: index}
+					if f.name == "" {
+						f.name = sf.Name
+					}
+					all = append(all, f)
+					continue
+				}
+
+				next = append(next, queued{typ: ft, index: index})
+			}
+		}
+		for typ := range level {
+			visited[typ] = true
+		}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		if len(all[i].index) != len(all[j].index) {
+			return len(all[i].index) < len(all[j].index)
+		}
+		return all[i].tagged && !all[j].tagged
+	})
+
+	fs := &structFields{byName: map[string]int{}}
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		if f, ok := dominantField(all[i:j]); ok {
+			fs.list = append(fs.list, f)
+		}
+		i = j
+	}
+
+	sort.Slice(fs.list, func(i, j int) bool {
+		a, b := fs.list[i].index, fs.list[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	for i, f := range fs.list {
+		fs.byName[f.name] = i
+	}
+
+	return fs
+}
+
+// dominantField выбирает поле среди одноименных; отсортированы по глубине и наличию тэга
+func dominantField(fields []field) (field, bool) {
+	if len(fields) > 1 && len(fields[0].index) == len(fields[1].index) && fields[0].tagged == fields[1].tagged {
+		return field{}, false
+	}
+	return fields[0], true
+}
+
+func parseJSONTag(tag string) (string, string) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, opts
+}
+
+func hasOption(opts, name string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
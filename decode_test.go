@@ -0,0 +1,111 @@
+package testparcer
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testDecodeInner struct {
+	Value int `json:"value"`
+}
+
+type testDecodeEmbedded struct {
+	Promoted string `json:"promoted"`
+	Shadowed string `json:"name"`
+}
+
+type testDecodeStruct struct {
+	testDecodeEmbedded
+	Name     string `json:"name"`
+	Untagged bool
+	Skipped  string                      `json:"-"`
+	Quoted   int                         `json:"quoted,string"`
+	Ptr      *testDecodeInner            `json:"ptr"`
+	Slice    []testDecodeInner           `json:"slice"`
+	Array    [2]int                      `json:"array"`
+	Map      map[string]*testDecodeInner `json:"map"`
+	IntMap   map[int]*testDecodeInner    `json:"int-map"`
+	UintMap  map[uint8]string            `json:"uint-map"`
+	Any      interface{}                 `json:"any"`
+	Raw      json.RawMessage             `json:"raw"`
+	Bytes    []byte                      `json:"bytes"`
+	Number   json.Number                 `json:"number"`
+}
+
+func Test_decodeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			"all kinds",
+			`{"promoted": "p", "name": "n", "untagged": true, "quoted": "12", "ptr": {"value": 1},
+			"slice": [{"value": 2}], "array": [3, 4], "map": {"k": {"value": 4}, "nil": null},
+			"any": {"x": [1, "y"]}, "raw": {"z": 1}, "bytes": "aGk=", "number": 5.5}`,
+			false,
+		},
+		{"null values", `{"ptr": null, "slice": null, "map": null, "name": null}`, false},
+		{"case insensitive keys", `{"NAME": "n", "PROMOTED": "p", "UNTAGGED": true}`, false},
+		{"unknown field", `{"unknown": 1}`, true},
+		{"skipped field", `{"Skipped": "x"}`, true},
+		{"string into int", `{"ptr": {"value": "1"}}`, true},
+		{"overflow", `{"array": [1e100, 1]}`, true},
+		{"object into slice", `{"slice": {}}`, true},
+		{"bad quoted", `{"quoted": "x"}`, true},
+		{"number map keys", `{"int-map": {"-1": {"value": 1}}, "uint-map": {"255": "x"}}`, false},
+		{"unknown field in number map", `{"int-map": {"1": {"value": 1, "zzz": 2}}}`, true},
+		{"bad map key", `{"int-map": {"x": null}}`, true},
+		{"map key overflow", `{"uint-map": {"256": "x"}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := &testDecodeStruct{}
+			d := json.NewDecoder(strings.NewReader(tt.data))
+			d.DisallowUnknownFields()
+			wantErr := d.Decode(want)
+
+			got := &testDecodeStruct{}
+			_, err := decodeJSON("", []byte(tt.data), got, defaultParser, nil)
+			if (err != nil) != tt.wantErr || (wantErr != nil) != tt.wantErr {
+				t.Fatalf("decodeJSON() error = %v, encoding/json error = %v, wantErr %v", err, wantErr, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("decodeJSON() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func Test_decodeJSON_arrayLength(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want FieldError
+		pos  string
+	}{
+		{"short", `{"array": [1]}`, FieldError{Field: "Array[1]", Path: "/array/1", Tag: "1", Kind: KindLength, Value: 1}, "1:13"},
+		{"long", `{"array": [1, 2, 3]}`, FieldError{Field: "Array[2]", Path: "/array/2", Tag: "2", Kind: KindLength, Value: 3}, "1:18"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeJSON("", []byte(tt.data), &testDecodeStruct{}, defaultParser, nil)
+
+			var de *DecodeError
+			var fe *FieldError
+			if !errors.As(err, &de) || !errors.As(err, &fe) {
+				t.Fatalf("decodeJSON() error = %v, want *DecodeError with *FieldError", err)
+			}
+			if de.Pos.String() != tt.pos {
+				t.Errorf("decodeJSON() position = %v, want %v", de.Pos, tt.pos)
+			}
+			if fe.Field != tt.want.Field || fe.Path != tt.want.Path || fe.Tag != tt.want.Tag ||
+				fe.Kind != tt.want.Kind || fe.Value != tt.want.Value {
+				t.Errorf("decodeJSON() error = %+v, want %+v", *fe, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package testparcer
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Position место в исходном json. Line и Column считаются с единицы, Column — в байтах
+type Position struct {
+	File   string
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	s := fmt.Sprintf("%d:%d", p.Line, p.Column)
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+	return s
+}
+
+// IsValid сообщает, известна ли позиция
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// position переводит смещение в data в номер строки и столбца
+func position(file string, data []byte, offset int) Position {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return Position{File: file, Offset: offset, Line: line, Column: column}
+}
+
+// DecodeError ошибка разбора json: синтаксическая ошибка, неподходящий тип значения
+// или неизвестный ключ. Err содержит исходную ошибку, например *json.UnmarshalTypeError
+type DecodeError struct {
+	Pos Position
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return e.Pos.String() + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// unknownFieldsError неизвестные ключи в режиме WarnUnknownFields без обработчика.
+// Если Parce вернул только ее, цель заполнена полностью
+type unknownFieldsError struct {
+	err error
+}
+
+func (e *unknownFieldsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unknownFieldsError) Unwrap() error {
+	return e.err
+}
+
+// Kind вид ошибки в поле
+type Kind string
+
+const (
+	KindRequired      Kind = "required"      // обязательное поле отсутствует
+	KindNull          Kind = "null"          // обязательное поле, не являющееся указателем, равно null
+	KindDefault       Kind = "default"       // значение тэга default нельзя записать в поле
+	KindUnaddressable Kind = "unaddressable" // структуры в отображении должны храниться по указателю
+	KindUnknown       Kind = "unknown"       // в цели нет поля для ключа json
+	KindTag           Kind = "tag"           // некорректный тэг поля цели
+	KindValidate      Kind = "validate"      // метод Validate значения вернул ошибку
+	KindLength        Kind = "length"        // длина массива json не совпадает с длиной массива go
+	KindMin           Kind = "min"           // значение или длина меньше min
+	KindMax           Kind = "max"           // значение или длина больше max
+	KindLen           Kind = "len"           // длина не равна len
+	KindOneOf         Kind = "oneof"         // значение не входит в oneof
+	KindPattern       Kind = "pattern"       // строка не подходит под pattern
+	KindEqField       Kind = "eqfield"       // значение не равно другому полю
+	KindGtField       Kind = "gtfield"       // значение не больше другого поля
+	KindGteField      Kind = "gtefield"      // значение меньше другого поля
+	KindLtField       Kind = "ltfield"       // значение не меньше другого поля
+	KindLteField      Kind = "ltefield"      // значение больше другого поля
+)
+
+// FieldError ошибка в конкретном поле цели. Достается из ошибки Parce через errors.As,
+// а все такие ошибки разом — через FieldErrors
+type FieldError struct {
+	Field string      // путь до поля в go, например F8[foo].F2
+	Path  string      // JSON Pointer до значения, например /struct-map-field/foo/string-field
+	Tag   string      // имя ключа в json
+	Kind  Kind        // вид ошибки
+	Value interface{} // значение, на котором произошла ошибка, например текст тэга default
+	Err   error       // причина ошибки, если есть
+	Pos   Position    // значение поля, а если его нет — объект, в котором поле должно быть
+}
+
+func (e *FieldError) Error() string {
+	var msg string
+	switch e.Kind {
+	case KindRequired:
+		msg = fmt.Sprintf(`required field "%v" (path "%v") is missing`, e.Field, e.Path)
+	case KindNull:
+		msg = fmt.Sprintf(`required field "%v" (path "%v") is null`, e.Field, e.Path)
+	case KindDefault:
+		msg = fmt.Sprintf(`invalid default value %q of field "%v" (path "%v")`, e.Value, e.Field, e.Path)
+	case KindUnknown:
+		msg = fmt.Sprintf(`unknown field "%v" (path "%v")`, e.Tag, e.Path)
+	case KindLength:
+		msg = fmt.Sprintf(`array length mismatch at "%v" (path "%v")`, e.Field, e.Path)
+	case KindValidate:
+		msg = "invalid value"
+		if e.Field != "" {
+			msg = fmt.Sprintf(`invalid field "%v" (path "%v")`, e.Field, e.Path)
+		}
+	case KindTag:
+		msg = fmt.Sprintf(`invalid tag of field "%v"`, e.Field)
+	case KindUnaddressable:
+		msg = fmt.Sprintf(`unaddressable field "%v" (path "%v") must be pointer`, e.Field, e.Path)
+	default:
+		msg = fmt.Sprintf(`field "%v" (path "%v"): %v`, e.Field, e.Path, e.Kind)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Pos.IsValid() {
+		msg = e.Pos.String() + ": " + msg
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors возвращает все ошибки полей, содержащиеся в err
+func FieldErrors(err error) []*FieldError {
+	switch e := err.(type) {
+	case *FieldError:
+		return []*FieldError{e}
+	case interface{ Unwrap() []error }:
+		var all []*FieldError
+		for _, err := range e.Unwrap() {
+			all = append(all, FieldErrors(err)...)
+		}
+		return all
+	case interface{ Unwrap() error }:
+		return FieldErrors(e.Unwrap())
+	}
+	return nil
+}
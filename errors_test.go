@@ -0,0 +1,135 @@
+package testparcer
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+type testPointerStruct struct {
+	Items map[string]*testDefaultStruct `json:"a/b~c"`
+}
+
+func TestFieldError(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		target interface{}
+		want   FieldError
+	}{
+		{
+			"nested map",
+			`{"byte-field": 1, "string-field": "a", "slice-field": ["a"], "struct-field": {"string-field": "a"},
+			"struct-map-field": {"foo": {"byte-field": 1}}}`,
+			&testStruct{},
+			FieldError{Field: "F8[foo].F2", Path: "/struct-map-field/foo/string-field", Tag: "string-field", Kind: KindRequired},
+		},
+		{
+			"escaped pointer",
+			`{"a/b~c": {"x": {"age": 1}}}`,
+			&testPointerStruct{},
+			FieldError{Field: "Items[x].Name", Path: "/a~1b~0c/x/name", Tag: "name", Kind: KindRequired},
+		},
+		{
+			"default",
+			`{"byte-field": 1, "string-field": "a", "slice-field": ["a"], "struct-field": {"string-field": "a"},
+			"struct-map-field": {}}`,
+			&testStruct{},
+			FieldError{Field: "F4", Path: "/int2-field", Tag: "int2-field", Kind: KindDefault, Value: "foo"},
+		},
+		{
+			"null",
+			`{"byte-field": 1, "string-field": null}`,
+			&testStruct{},
+			FieldError{Field: "F2", Path: "/string-field", Tag: "string-field", Kind: KindNull},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), tt.target)
+
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				t.Fatalf("ParceBytes() error = %v, want *FieldError", err)
+			}
+			if fe.Field != tt.want.Field || fe.Path != tt.want.Path || fe.Tag != tt.want.Tag ||
+				fe.Kind != tt.want.Kind || fe.Value != tt.want.Value {
+				t.Errorf("ParceBytes() error = %+v, want %+v", *fe, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldError_Unwrap(t *testing.T) {
+	err := ParceBytes([]byte(`{}`), &testStructDefaultFieldsWrongInt{})
+
+	var numErr *strconv.NumError
+	if !errors.Is(err, ErrorWhileUnmarshaling) || !errors.As(err, &numErr) {
+		t.Errorf("ParceBytes() error = %v, want wrapped %v and *strconv.NumError", err, ErrorWhileUnmarshaling)
+	}
+	if got := len(FieldErrors(err)); got != 1 {
+		t.Errorf("FieldErrors() returned %d errors, want 1", got)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		name          string
+		parce         func() error
+		want          Position
+		isDecodeError bool
+	}{
+		{
+			"string into int",
+			func() error { return ParceFS(testFS, "test1.json", &testStruct{}) },
+			Position{File: "test1.json", Line: 13, Column: 16},
+			true,
+		},
+		{
+			"required field is null",
+			func() error { return ParceFS(testFS, "test2.json", &testStruct{}) },
+			Position{File: "test2.json", Line: 6, Column: 20},
+			false,
+		},
+		{
+			"required field in nested object",
+			func() error {
+				return ParceBytes([]byte("{\n  \"name\": \"a\",\n  \"parent\": {\"age\": 1}\n}"), &testStructWithNested{})
+			},
+			Position{Line: 3, Column: 13},
+			false,
+		},
+		{
+			"unknown field",
+			func() error { return ParceBytes([]byte("{\n  \"name\": \"a\",\n  \"x\": 1}"), &testStructWithNested{}) },
+			Position{Line: 3, Column: 3},
+			true,
+		},
+		{
+			"syntax error",
+			func() error { return ParceBytes([]byte("{\n  \"name\": \"a\",\n  \"x\": }"), &testStructWithNested{}) },
+			Position{Line: 3, Column: 8},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parce()
+
+			var got Position
+			var de *DecodeError
+			var fe *FieldError
+			switch {
+			case errors.As(err, &de) && tt.isDecodeError:
+				got = de.Pos
+			case errors.As(err, &fe) && !tt.isDecodeError:
+				got = fe.Pos
+			default:
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got.File != tt.want.File || got.Line != tt.want.Line || got.Column != tt.want.Column {
+				t.Errorf("position = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
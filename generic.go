@@ -0,0 +1,63 @@
+package testparcer
+
+import (
+	"io"
+	"io/fs"
+	"reflect"
+)
+
+// Parse разбирает файл filepath в новое значение типа T и возвращает его.
+// T — структура или срез структур либо указатель на них; для указателя значение создается.
+// При ошибке возвращается нулевое значение, кроме ошибки ErrorUnknownFields в режиме
+// WarnUnknownFields: тогда значение заполнено
+func Parse[T any](filepath string, opts ...Option) (T, error) {
+	return parseInto[T](func(target interface{}) error {
+		return Parce(filepath, target, opts...)
+	})
+}
+
+// ParseReader как Parse, но читает json из r
+func ParseReader[T any](r io.Reader, opts ...Option) (T, error) {
+	return parseInto[T](func(target interface{}) error {
+		return ParceReader(r, target, opts...)
+	})
+}
+
+// ParseFS как Parse, но читает файл name из fsys
+func ParseFS[T any](fsys fs.FS, name string, opts ...Option) (T, error) {
+	return parseInto[T](func(target interface{}) error {
+		return ParceFS(fsys, name, target, opts...)
+	})
+}
+
+// ParseBytes как Parse, но берет json из data
+func ParseBytes[T any](data []byte, opts ...Option) (T, error) {
+	return parseInto[T](func(target interface{}) error {
+		return ParceBytes(data, target, opts...)
+	})
+}
+
+// parseInto создает значение типа T и передает parse указатель на него
+func parseInto[T any](parse func(target interface{}) error) (T, error) {
+	var v, zero T
+
+	target := interface{}(&v)
+	if rv := reflect.ValueOf(&v).Elem(); rv.Kind() == reflect.Ptr {
+		if err := checkTargetType(rv.Type().Elem()); err != nil {
+			return zero, err
+		}
+		rv.Set(reflect.New(rv.Type().Elem()))
+		target = rv.Interface()
+	}
+
+	err := parse(target)
+	if _, warn := err.(*unknownFieldsError); warn {
+		// только неизвестные ключи: значение заполнено и возвращается вместе с ошибкой
+		return v, err
+	}
+	if err != nil {
+		return zero, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,90 @@
+package testparcer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	data := []byte(`{"name": "jin"}`)
+
+	got, err := ParseBytes[testDefaultStruct](data)
+	if err != nil {
+		t.Fatalf("ParseBytes[testDefaultStruct]() error = %v", err)
+	}
+	if want := (testDefaultStruct{Name: "jin", Age: 18}); got != want {
+		t.Errorf("ParseBytes[testDefaultStruct]() = %+v, want %+v", got, want)
+	}
+
+	ptr, err := ParseBytes[*testDefaultStruct](data)
+	if err != nil {
+		t.Fatalf("ParseBytes[*testDefaultStruct]() error = %v", err)
+	}
+	if want := (&testDefaultStruct{Name: "jin", Age: 18}); !reflect.DeepEqual(ptr, want) {
+		t.Errorf("ParseBytes[*testDefaultStruct]() = %+v, want %+v", ptr, want)
+	}
+
+	got, err = ParseBytes[testDefaultStruct]([]byte(`{}`))
+	if !errors.Is(err, ErrorWhileChekingRequired) || got != (testDefaultStruct{}) {
+		t.Errorf("ParseBytes[testDefaultStruct]() = %+v, %v, want zero value and %v", got, err, ErrorWhileChekingRequired)
+	}
+}
+
+func TestParseBytes_warnUnknownFields(t *testing.T) {
+	type sWarn struct {
+		A int `json:"a"`
+	}
+	data := []byte(`{"a": 1, "b": 2}`)
+
+	got, err := ParseBytes[sWarn](data, WithUnknownFields(WarnUnknownFields))
+	if !errors.Is(err, ErrorUnknownFields) || got.A != 1 {
+		t.Errorf("ParseBytes[sWarn]() = %+v, %v, want {A:1} and %v", got, err, ErrorUnknownFields)
+	}
+
+	ptr, err := ParseBytes[*sWarn](data, WithUnknownFields(WarnUnknownFields))
+	if !errors.Is(err, ErrorUnknownFields) || ptr == nil || ptr.A != 1 {
+		t.Errorf("ParseBytes[*sWarn]() = %+v, %v, want {A:1} and %v", ptr, err, ErrorUnknownFields)
+	}
+
+	// вместе с другой ошибкой значение не возвращается
+	type sRequired struct {
+		A int `json:"a"`
+		C int `json:"c,required"`
+	}
+	req, err := ParseBytes[sRequired](data, WithUnknownFields(WarnUnknownFields), WithAllErrors())
+	if !errors.Is(err, ErrorUnknownFields) || !errors.Is(err, ErrorWhileChekingRequired) || req != (sRequired{}) {
+		t.Errorf("ParseBytes[sRequired]() = %+v, %v", req, err)
+	}
+}
+
+func TestParseBytes_unsupported(t *testing.T) {
+	data := []byte(`{"name": "jin"}`)
+
+	tests := []struct {
+		name  string
+		parse func() error
+	}{
+		{"int", func() error { _, err := ParseBytes[int](data); return err }},
+		{"map", func() error { _, err := ParseBytes[map[string]string](data); return err }},
+		{"pointer to pointer", func() error { _, err := ParseBytes[**testDefaultStruct](data); return err }},
+		{"interface", func() error { _, err := ParseBytes[interface{}](data); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.parse(); !errors.Is(err, ErrorUnsupportedTarget) {
+				t.Errorf("error = %v, want %v", err, ErrorUnsupportedTarget)
+			}
+		})
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	got, err := ParseFS[anotherTestStruct3](testFS, "test5.json")
+	if err != nil {
+		t.Fatalf("ParseFS() error = %v", err)
+	}
+	if len(got.F5) != 3 || got.F5[0].F2 != "foo" {
+		t.Errorf("ParseFS() = %+v", got)
+	}
+}
@@ -0,0 +1,457 @@
+package testparcer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// maxDepth ограничивает вложенность документа, как и encoding/json
+const maxDepth = 10000
+
+type nodeKind uint8
+
+const (
+	nodeNull nodeKind = iota
+	nodeBool
+	nodeNumber
+	nodeString
+	nodeObject
+	nodeArray
+)
+
+// node узел дерева разобранного json. Значения не копируются: узел хранит только
+// границы значения в исходных данных и вложенные узлы объектов и массивов
+type node struct {
+	kind    nodeKind
+	start   int
+	end     int
+	members []member // ключи объекта или элементы массива
+	index   map[string]*node
+}
+
+// member ключ объекта или элемент массива
+type member struct {
+	key   string // пусто у элементов массива
+	start int    // смещение ключа или элемента
+	val   *node
+}
+
+// field возвращает значение ключа объекта или nil, если ключа нет.
+// При повторяющихся ключах побеждает последний, как в encoding/json
+func (n *node) field(key string) *node {
+	if n == nil || n.kind != nodeObject {
+		return nil
+	}
+	if len(n.members) > 8 {
+		if n.index == nil {
+			n.index = make(map[string]*node, len(n.members))
+			for _, m := range n.members {
+				n.index[m.key] = m.val
+			}
+		}
+		return n.index[key]
+	}
+	for i := len(n.members) - 1; i >= 0; i-- {
+		if n.members[i].key == key {
+			return n.members[i].val
+		}
+	}
+	return nil
+}
+
+// elem возвращает i-й элемент массива или nil
+func (n *node) elem(i int) *node {
+	if n == nil || n.kind != nodeArray || i >= len(n.members) {
+		return nil
+	}
+	return n.members[i].val
+}
+
+func (n *node) kindName() string {
+	switch n.kind {
+	case nodeBool:
+		return "bool"
+	case nodeNumber:
+		return "number"
+	case nodeString:
+		return "string"
+	case nodeObject:
+		return "object"
+	case nodeArray:
+		return "array"
+	}
+	return "null"
+}
+
+type syntaxError struct {
+	msg    string
+	offset int
+}
+
+func (e *syntaxError) Error() string {
+	return e.msg
+}
+
+// parseTree разбирает data в дерево node за один проход
+func parseTree(data []byte) (*node, error) {
+	s := scanner{data: data}
+	s.skipSpace()
+	n, err := s.value()
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if s.pos < len(s.data) {
+		return nil, s.errorf("invalid character %s after top-level value", quoteChar(s.data[s.pos]))
+	}
+	return n, nil
+}
+
+type scanner struct {
+	data  []byte
+	pos   int
+	depth int
+
+	// узлы выделяются пачками, а элементы объектов и массивов копятся в общем стеке
+	// и, когда контейнер закрыт, переносятся в срез из общего блока
+	slab        []node
+	members     []member
+	memberBlock []member
+}
+
+// slabSize число узлов или элементов в одном блоке
+const slabSize = 256
+
+func (s *scanner) newNode(kind nodeKind) *node {
+	if len(s.slab) == 0 {
+		s.slab = make([]node, slabSize)
+	}
+	n := &s.slab[0]
+	s.slab = s.slab[1:]
+	n.kind = kind
+	n.start = s.pos
+	return n
+}
+
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return &syntaxError{msg: fmt.Sprintf(format, args...), offset: s.pos}
+}
+
+func (s *scanner) eof() error {
+	return &syntaxError{msg: "unexpected end of JSON input", offset: len(s.data)}
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *scanner) value() (*node, error) {
+	if s.pos >= len(s.data) {
+		return nil, s.eof()
+	}
+	switch c := s.data[s.pos]; {
+	case c == '{':
+		return s.object()
+	case c == '[':
+		return s.array()
+	case c == '"':
+		return s.string()
+	case c == '-' || c >= '0' && c <= '9':
+		return s.number()
+	case c == 't':
+		return s.literal("true", nodeBool)
+	case c == 'f':
+		return s.literal("false", nodeBool)
+	case c == 'n':
+		return s.literal("null", nodeNull)
+	default:
+		return nil, s.errorf("invalid character %s looking for beginning of value", quoteChar(c))
+	}
+}
+
+func (s *scanner) enter() error {
+	s.depth++
+	if s.depth > maxDepth {
+		return s.errorf("exceeded max depth")
+	}
+	return nil
+}
+
+func (s *scanner) object() (*node, error) {
+	if err := s.enter(); err != nil {
+		return nil, err
+	}
+	n := s.newNode(nodeObject)
+	mark := len(s.members)
+	s.pos++
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == '}' {
+		s.pos++
+		n.end = s.pos
+		s.depth--
+		return n, nil
+	}
+	for {
+		if s.pos >= len(s.data) {
+			return nil, s.eof()
+		}
+		if s.data[s.pos] != '"' {
+			return nil, s.errorf("invalid character %s looking for beginning of object key string", quoteChar(s.data[s.pos]))
+		}
+		start := s.pos
+		if err := s.skipString(); err != nil {
+			return nil, err
+		}
+		key, err := unquote(s.data[start:s.pos])
+		if err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return nil, s.eof()
+		}
+		if s.data[s.pos] != ':' {
+			return nil, s.errorf("invalid character %s after object key", quoteChar(s.data[s.pos]))
+		}
+		s.pos++
+		s.skipSpace()
+		v, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		s.members = append(s.members, member{key: key, start: start, val: v})
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return nil, s.eof()
+		}
+		switch s.data[s.pos] {
+		case ',':
+			s.pos++
+			s.skipSpace()
+		case '}':
+			s.pos++
+			n.end = s.pos
+			n.members = s.takeMembers(mark)
+			s.depth--
+			return n, nil
+		default:
+			return nil, s.errorf("invalid character %s after object key:value pair", quoteChar(s.data[s.pos]))
+		}
+	}
+}
+
+func (s *scanner) array() (*node, error) {
+	if err := s.enter(); err != nil {
+		return nil, err
+	}
+	n := s.newNode(nodeArray)
+	mark := len(s.members)
+	s.pos++
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == ']' {
+		s.pos++
+		n.end = s.pos
+		s.depth--
+		return n, nil
+	}
+	for {
+		v, err := s.value()
+		if err != nil {
+			return nil, err
+		}
+		s.members = append(s.members, member{start: v.start, val: v})
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return nil, s.eof()
+		}
+		switch s.data[s.pos] {
+		case ',':
+			s.pos++
+			s.skipSpace()
+		case ']':
+			s.pos++
+			n.end = s.pos
+			n.members = s.takeMembers(mark)
+			s.depth--
+			return n, nil
+		default:
+			return nil, s.errorf("invalid character %s after array element", quoteChar(s.data[s.pos]))
+		}
+	}
+}
+
+// takeMembers переносит элементы закрытого контейнера с вершины стека, начиная с mark,
+// в общий блок, чтобы не выделять память под каждый объект отдельно
+func (s *scanner) takeMembers(mark int) []member {
+	l := len(s.members) - mark
+	if len(s.memberBlock) < l {
+		s.memberBlock = make([]member, max(l, slabSize))
+	}
+	m := s.memberBlock[:l:l]
+	s.memberBlock = s.memberBlock[l:]
+	copy(m, s.members[mark:])
+	s.members = s.members[:mark]
+	return m
+}
+
+func (s *scanner) string() (*node, error) {
+	n := s.newNode(nodeString)
+	if err := s.skipString(); err != nil {
+		return nil, err
+	}
+	n.end = s.pos
+	return n, nil
+}
+
+func (s *scanner) skipString() error {
+	s.pos++
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c == '"':
+			s.pos++
+			return nil
+		case c == '\\':
+			s.pos++
+			if s.pos >= len(s.data) {
+				return s.eof()
+			}
+			switch s.data[s.pos] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				s.pos++
+			case 'u':
+				s.pos++
+				for i := 0; i < 4; i++ {
+					if s.pos >= len(s.data) {
+						return s.eof()
+					}
+					if !isHex(s.data[s.pos]) {
+						return s.errorf("invalid character %s in \\u hexadecimal character escape", quoteChar(s.data[s.pos]))
+					}
+					s.pos++
+				}
+			default:
+				return s.errorf("invalid character %s in string escape code", quoteChar(s.data[s.pos]))
+			}
+		case c < 0x20:
+			return s.errorf("invalid character %s in string literal", quoteChar(c))
+		default:
+			s.pos++
+		}
+	}
+	return s.eof()
+}
+
+// unquote принимает строку json вместе с кавычками и возвращает ее содержимое
+func unquote(raw []byte) (string, error) {
+	if str, ok := unquoteFast(raw); ok {
+		return str, nil
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return "", err
+	}
+	return str, nil
+}
+
+// unquoteFast снимает кавычки со строки без escape-последовательностей
+func unquoteFast(raw []byte) (string, bool) {
+	raw = raw[1 : len(raw)-1]
+	if bytes.IndexByte(raw, '\\') >= 0 || !utf8.Valid(raw) {
+		return "", false
+	}
+	return string(raw), true
+}
+
+func (s *scanner) number() (*node, error) {
+	n := s.newNode(nodeNumber)
+	if s.data[s.pos] == '-' {
+		s.pos++
+	}
+	if s.pos >= len(s.data) {
+		return nil, s.eof()
+	}
+	switch c := s.data[s.pos]; {
+	case c == '0':
+		s.pos++
+	case c >= '1' && c <= '9':
+		s.digits()
+	default:
+		return nil, s.errorf("invalid character %s in numeric literal", quoteChar(c))
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '.' {
+		s.pos++
+		if s.pos >= len(s.data) {
+			return nil, s.eof()
+		}
+		if !isDigit(s.data[s.pos]) {
+			return nil, s.errorf("invalid character %s after decimal point in numeric literal", quoteChar(s.data[s.pos]))
+		}
+		s.digits()
+	}
+	if s.pos < len(s.data) && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		s.pos++
+		if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+		if s.pos >= len(s.data) {
+			return nil, s.eof()
+		}
+		if !isDigit(s.data[s.pos]) {
+			return nil, s.errorf("invalid character %s in exponent of numeric literal", quoteChar(s.data[s.pos]))
+		}
+		s.digits()
+	}
+	n.end = s.pos
+	return n, nil
+}
+
+func (s *scanner) digits() {
+	for s.pos < len(s.data) && isDigit(s.data[s.pos]) {
+		s.pos++
+	}
+}
+
+func (s *scanner) literal(lit string, kind nodeKind) (*node, error) {
+	n := s.newNode(kind)
+	for i := 0; i < len(lit); i++ {
+		if s.pos >= len(s.data) {
+			return nil, s.eof()
+		}
+		if s.data[s.pos] != lit[i] {
+			return nil, s.errorf("invalid character %s in literal %s (expecting %s)", quoteChar(s.data[s.pos]), lit, quoteChar(lit[i]))
+		}
+		s.pos++
+	}
+	n.end = s.pos
+	return n, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func quoteChar(c byte) string {
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+	s := strconv.Quote(string(c))
+	return "'" + s[1:len(s)-1] + "'"
+}
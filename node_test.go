@@ -0,0 +1,51 @@
+package testparcer
+
+import (
+	"testing"
+)
+
+func Test_parseTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"object", `{"a": 1, "b": [true, false, null], "c": {"d": "eé\n"}}`, false},
+		{"number", `-12.5e+3`, false},
+		{"empty", ``, true},
+		{"trailing comma", `{"a": 1,}`, true},
+		{"unterminated string", `{"a": "b}`, true},
+		{"bad escape", `"\x"`, true},
+		{"bad literal", `nul`, true},
+		{"leading zero", `01`, true},
+		{"after top-level value", `{} {}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTree([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseTree() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_node_field(t *testing.T) {
+	n, err := parseTree([]byte(`{"a": 1, "b": {"c": null}, "a": "last", "d": [1, {"e": 2}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := n.field("a"); got == nil || got.kind != nodeString {
+		t.Errorf(`field("a") = %v, want last string value`, got)
+	}
+	if got := n.field("b").field("c"); got == nil || got.kind != nodeNull {
+		t.Errorf(`field("b").field("c") = %v, want null`, got)
+	}
+	if got := n.field("d").elem(1).field("e"); got == nil || got.kind != nodeNumber {
+		t.Errorf(`field("d").elem(1).field("e") = %v, want number`, got)
+	}
+	if got := n.field("x").field("y").elem(3); got != nil {
+		t.Errorf("lookup of missing key = %v, want nil", got)
+	}
+}
@@ -1,14 +1,17 @@
 package testparcer
 
 import (
-	"bufio"
-	"encoding/json"
+	"encoding"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/fs"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -16,286 +19,805 @@ var (
 	ErrorWhileUnmarshaling    = errors.New("error while unmarshaling")
 	ErrorWhileChekingRequired = errors.New("error while cheking requiered fields")
 	ErrorWhileSettingDefault  = errors.New("error while setting fields")
+	ErrorWhileValidating      = errors.New("error while validating")
+	ErrorUnsupportedTarget    = errors.New("unsupported target type")
+	ErrorInvalidTag           = errors.New("invalid tag")
+	ErrorInvalidOption        = errors.New("invalid option")
+	ErrorUnknownFields        = errors.New("unknown fields")
 )
 
-// Parce принимает путь файла и с труктуру в которю распарсит json, возвращает ошибку
-func Parce(filepath string, target interface{}) error {
-	m := make(map[string]interface{})
-	err := readJSON(filepath, target)
-	if err != nil {
-		err := fmt.Errorf("%w: %v", ErrorWhileReadingFile, err)
-		return err
+// Parce принимает путь файла и с труктуру в которю распарсит json, возвращает ошибку.
+// Без опций используется Parser по умолчанию, с опциями — созданный через NewParser(opts...)
+func Parce(filepath string, target interface{}, opts ...Option) error {
+	return parserFor(opts).Parce(filepath, target)
+}
+
+// ParceReader как Parce, но читает json из r, например из тела http запроса
+func ParceReader(r io.Reader, target interface{}, opts ...Option) error {
+	return parserFor(opts).ParceReader(r, target)
+}
+
+// ParceFS как Parce, но читает файл name из fsys, например из embed.FS или os.DirFS
+func ParceFS(fsys fs.FS, name string, target interface{}, opts ...Option) error {
+	return parserFor(opts).ParceFS(fsys, name, target)
+}
+
+// ParceBytes как Parce, но берет json из data
+func ParceBytes(data []byte, target interface{}, opts ...Option) error {
+	return parserFor(opts).ParceBytes(data, target)
+}
+
+// walker обходит цель вместе с деревом node, запоминая путь до текущего поля.
+// Без allErrors обход прекращается на первой ошибке
+type walker struct {
+	p       *Parser
+	path    []step
+	errs    []error
+	invalid []error               // значения, не прошедшие ограничения из тэгов
+	seen    map[seenKey]bool      // указатели, уже пройденные setDefaultFields или callValidators
+	alloc   map[reflect.Type]bool // типы указателей, созданных WithAllocStructs на текущем пути
+	keys    map[seenKey]mapKeys   // отсортированные ключи отображений, общие для всех проходов
+	names   []string              // стек ключей полей из fieldKeys
+	top     reflect.Value         // корень цели или элемент среза для путей в правилах сравнения
+	topPath []step                // путь до top от корня цели
+
+	// исходный документ, по нему вычисляются позиции ошибок
+	file string
+	data []byte
+	root *node
+}
+
+// step шаг пути от корня до текущего поля
+type step struct {
+	field string // имя поля в go, либо индекс или ключ в квадратных скобках
+	token string // ключ или индекс в json
+	at    *node  // значение в json, если оно есть
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// stepPaths возвращает путь в go и JSON Pointer для шагов steps
+func stepPaths(steps []step) (string, string) {
+	var field, path strings.Builder
+	for _, s := range steps {
+		if field.Len() > 0 && s.field != "" && !strings.HasPrefix(s.field, "[") {
+			field.WriteByte('.')
+		}
+		field.WriteString(s.field)
+		path.WriteByte('/')
+		pointerEscaper.WriteString(&path, s.token)
 	}
-	err = readJSON(filepath, &m)
-	if err != nil {
-		err := fmt.Errorf("%w: %v", ErrorWhileReadingFile, err)
-		return err
+	return field.String(), path.String()
+}
+
+func (w *walker) enter(s step) {
+	w.path = append(w.path, s)
+}
+
+func (w *walker) leave() {
+	w.path = w.path[:len(w.path)-1]
+}
+
+// enterElem переходит к элементу key среза или отображения из поля sf.
+// at и elem — значения поля и элемента в json
+func (w *walker) enterElem(sf reflect.StructField, name, key string, at, elem *node) {
+	w.path = append(w.path,
+		step{field: sf.Name, token: name, at: at},
+		step{field: "[" + key + "]", token: key, at: elem},
+	)
+}
+
+func (w *walker) leaveElem() {
+	w.path = w.path[:len(w.path)-2]
+}
+
+func (w *walker) fail(err error) {
+	w.errs = append(w.errs, err)
+}
+
+// fieldError создает ошибку для поля sf текущей структуры, записанной в n
+func (w *walker) fieldError(n *node, sf reflect.StructField, name string, kind Kind) *FieldError {
+	field, path := stepPaths(append(w.path, step{field: sf.Name, token: name}))
+	fe := &FieldError{
+		Field: field,
+		Path:  path,
+		Tag:   name,
+		Kind:  kind,
+	}
+	if w.data != nil {
+		fe.Pos = position(w.file, w.data, w.nearest(n.field(name), n).start)
 	}
+	return fe
+}
 
-	err = checkeRequiredFields(target, m)
-	if err != nil {
-		err := fmt.Errorf("%w: %v", ErrorWhileChekingRequired, err)
-		return err
+// requiredError создает ошибку обязательного поля; why — условие, по которому поле обязательно
+func (w *walker) requiredError(n *node, sf reflect.StructField, name string, kind Kind, why error) *FieldError {
+	fe := w.fieldError(n, sf, name, kind)
+	fe.Err = why
+	return fe
+}
+
+// nearest возвращает первый существующий из узлов, а если их нет — ближайший
+// существующий узел на пути от корня
+func (w *walker) nearest(nodes ...*node) *node {
+	for _, n := range nodes {
+		if n != nil {
+			return n
+		}
+	}
+	for i := len(w.path) - 1; i >= 0; i-- {
+		if w.path[i].at != nil {
+			return w.path[i].at
+		}
 	}
+	return w.root
+}
 
-	err = setDefaultFields(target, m)
-	if err != nil {
-		err := fmt.Errorf("%w: %v", ErrorWhileUnmarshaling, err)
-		return err
+func (w *walker) invalidate(err error) {
+	w.invalid = append(w.invalid, err)
+}
+
+// done сообщает, что обход пора прекратить
+func (w *walker) done() bool {
+	return !w.p.allErrors && len(w.errs)+len(w.invalid) > 0
+}
+
+func (w *walker) error() error {
+	return joinErrors(w.errs)
+}
+
+// invalidError возвращает ошибки ограничений значений
+func (w *walker) invalidError() error {
+	return joinErrors(w.invalid)
+}
+
+func joinErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
 	}
+	return errors.Join(errs...)
+}
 
-	return nil
+// walk применяет fn к корню цели: к структуре или к каждой структуре в срезе
+func (w *walker) walk(root reflect.Value, n *node, fn func(reflect.Value, *node)) {
+	if root.Kind() == reflect.Struct {
+		w.top = root
+		fn(root, n)
+		return
+	}
+
+	for i := 0; i < root.Len() && !w.done(); i++ {
+		v := reflect.Indirect(root.Index(i))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		key := strconv.Itoa(i)
+		w.enter(step{field: "[" + key + "]", token: key, at: n.elem(i)})
+		w.top, w.topPath = v, w.path
+		fn(v, n.elem(i))
+		w.leave()
+	}
+}
+
+// checkTarget проверяет, что target — ненулевой указатель на структуру или на срез структур
+func checkTarget(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	switch {
+	case target == nil:
+		return fmt.Errorf("%w: nil, want pointer to struct or to slice of structs", ErrorUnsupportedTarget)
+	case rv.Kind() != reflect.Ptr:
+		return fmt.Errorf("%w: %T is not a pointer", ErrorUnsupportedTarget, target)
+	case rv.IsNil():
+		return fmt.Errorf("%w: nil %T", ErrorUnsupportedTarget, target)
+	}
+	return checkTargetType(rv.Type().Elem())
 }
 
-func checkeRequiredFields(target interface{}, m interface{}) error {
-	fields := reflect.ValueOf(target).Elem()
-	check := reflect.ValueOf(m)
+// checkTargetType проверяет, что в значение типа t можно разобрать json:
+// это структура, срез или массив структур либо указателей на них
+func checkTargetType(t reflect.Type) error {
+	e := t
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		e = t.Elem()
+		if e.Kind() == reflect.Ptr {
+			e = e.Elem()
+		}
+	}
+	if e.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %v, want struct or slice of structs", ErrorUnsupportedTarget, t)
+	}
+	return nil
+}
 
-	for i := 0; i < fields.NumField(); i++ {
-		tagStr := fields.Type().Field(i).Tag.Get("json")
+// checkeRequiredFields проверяет обязательные поля структуры v, записанной в n,
+// и ограничения значений из json. Поля встроенных структур поднимаются наверх так же, как в encoding/json
+func (w *walker) checkeRequiredFields(v reflect.Value, n *node) {
+	fields := cachedFields(v.Type(), w.p.fieldTag)
+	rs, _ := w.p.cachedRules(v.Type())
+	keys, mark := w.fieldKeys(n, fields)
+	defer w.dropKeys(mark)
+	for i := 0; i < len(fields.list) && !w.done(); i++ {
+		fi := &fields.list[i]
+		sf := v.Type().FieldByIndex(fi.index)
+		required, why := w.requiredBy(rs[i], n, fields, keys)
+		name := keys[i]
+		f, ok := fieldValue(v, fi.index)
+		if !ok {
+			// встроенный указатель пуст, значит ключей его полей в json не было
+			f = reflect.Zero(sf.Type)
+		}
+		if at := n.field(name); at != nil && at.kind != nodeNull {
+			w.checkConstraints(n, sf, name, f, rs[i])
+		}
 
-		switch fields.Field(i).Kind() {
+		switch f.Kind() {
 		default:
-			if isFieldRequered(tagStr) &&
-				isRequeredFieldNil(check, tagStr) {
-				err := fmt.Sprintf(`required field "%v" (tag "%v") is missing`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0])
-				return errors.New(err)
+			if !required {
+				continue
+			}
+			// null оставляет нулевое значение, поэтому обязательное поле не заполнено
+			if isRequeredFieldNil(n, name) {
+				w.fail(w.requiredError(n, sf, name, KindRequired, why))
+			} else if n.field(name).kind == nodeNull {
+				w.fail(w.requiredError(n, sf, name, KindNull, why))
+			}
+		case reflect.Ptr:
+			// null в указателе — осознанное отсутствие значения
+			if required && isRequeredFieldNil(n, name) {
+				w.fail(w.requiredError(n, sf, name, KindRequired, why))
+				continue
+			}
+
+			if child := n.field(name); child != nil && isStructPtr(f) && !isUnmarshaler(f.Type().Elem()) {
+				w.enter(step{field: sf.Name, token: name, at: child})
+				w.checkeRequiredFields(f.Elem(), child)
+				w.leave()
 			}
 		case reflect.Struct:
-			if isFieldRequered(tagStr) && fields.Field(i).IsZero() {
-				err := fmt.Sprintf(`required field "%v" (tag "%v") is missing`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0])
-				return errors.New(err)
+			if required && f.IsZero() {
+				w.fail(w.requiredError(n, sf, name, KindRequired, why))
+				continue
 			}
 
-			if check.MapIndex(reflect.ValueOf(strings.Split(tagStr, ",")[0])).IsValid() {
-				err := checkeRequiredFields(fields.Field(i).Addr().Interface(), check.MapIndex(reflect.ValueOf(strings.Split(tagStr, ",")[0])).Interface())
-				if err != nil {
-					err := fmt.Errorf(`struct "%v" (tag "%v"): %w`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0], err)
-					return err
-				}
+			if child := n.field(name); child != nil {
+				w.enter(step{field: sf.Name, token: name, at: n.field(name)})
+				w.checkeRequiredFields(f, child)
+				w.leave()
 			}
 		case reflect.Map:
-			if isFieldRequered(tagStr) && fields.Field(i).IsZero() {
-				err := fmt.Sprintf(`required field "%v" (tag "%v") is missing`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0])
-				return errors.New(err)
+			if required && f.IsZero() {
+				w.fail(w.requiredError(n, sf, name, KindRequired, why))
+				continue
 			}
 
-			for _, key := range fields.Field(i).MapKeys() {
-				if !fields.Field(i).MapIndex(key).IsZero() {
-					switch fields.Field(i).MapIndex(key).Kind() {
-					case reflect.Struct:
-						err := fmt.Sprintf(`unaddressable field "%v" (tag "%v") must be pointer`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0])
-						return errors.New(err)
-					case reflect.Ptr:
-						err := checkeRequiredFields(fields.Field(i).MapIndex(key).Interface(), reflect.ValueOf(check.MapIndex(reflect.ValueOf(strings.Split(tagStr, ",")[0])).Interface()).MapIndex(key).Interface())
-						if err != nil {
-							err := fmt.Errorf(`map "%v" (tag "%v") key "%v" : %w`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0], key.Interface(), err)
-							return err
-						}
-					}
+			for _, key := range w.sortedKeys(f) {
+				if w.done() {
+					break
+				}
+				v := f.MapIndex(key)
+				if v.IsZero() {
+					continue
+				}
+				if v.Kind() == reflect.Struct {
+					w.fail(w.fieldError(n, sf, name, KindUnaddressable))
+					break
+				}
+				if isStructPtr(v) {
+					elem := n.field(name).field(mapKey(key))
+					w.enterElem(sf, name, mapKey(key), n.field(name), elem)
+					w.checkeRequiredFields(v.Elem(), elem)
+					w.leaveElem()
 				}
 			}
 		case reflect.Slice:
-			if isFieldRequered(tagStr) && fields.Field(i).IsZero() {
-				err := fmt.Sprintf(`required field "%v" (tag "%v") is missing`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0])
-				return errors.New(err)
+			if required && f.IsZero() {
+				w.fail(w.requiredError(n, sf, name, KindRequired, why))
+				continue
+			}
+
+			for j := 0; j < f.Len() && !w.done(); j++ {
+				v := f.Index(j)
+				if v.IsZero() || v.Kind() != reflect.Struct && !isStructPtr(v) {
+					continue
+				}
+				elem := n.field(name).elem(j)
+				w.enterElem(sf, name, strconv.Itoa(j), n.field(name), elem)
+				w.checkeRequiredFields(reflect.Indirect(v), elem)
+				w.leaveElem()
+			}
+		case reflect.Array:
+			// длина массива уже сверена при разборе, поэтому у каждого элемента есть значение в json
+			at := n.field(name)
+			if required && at == nil {
+				w.fail(w.requiredError(n, sf, name, KindRequired, why))
+				continue
+			}
+			if required && at.kind == nodeNull {
+				w.fail(w.requiredError(n, sf, name, KindNull, why))
+				continue
 			}
 
-			for j := 0; j < fields.Field(i).Len(); j++ {
-				if !fields.Field(i).Index(j).IsZero() {
-					switch fields.Field(i).Index(j).Kind() {
-					case reflect.Struct:
-						err := checkeRequiredFields(fields.Field(i).Index(j).Addr().Interface(), reflect.ValueOf(check.MapIndex(reflect.ValueOf(strings.Split(tagStr, ",")[0])).Interface()).Index(j).Interface())
-						if err != nil {
-							err := fmt.Errorf(`slice "%v" (tag "%v") index "%v" : %w`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0], j, err)
-							return err
-						}
-					case reflect.Ptr:
-						err := checkeRequiredFields(fields.Field(i).Index(j).Interface(), reflect.ValueOf(check.MapIndex(reflect.ValueOf(strings.Split(tagStr, ",")[0])).Interface()).Index(j).Interface())
-						if err != nil {
-							err := fmt.Errorf(`slice "%v" (tag "%v") index "%v" : %w`, fields.Type().Field(i).Name, strings.Split(tagStr, ",")[0], j, err)
-							return err
-						}
-					}
+			for j := 0; j < f.Len() && !w.done(); j++ {
+				v := f.Index(j)
+				elem := at.elem(j)
+				if elem == nil || v.Kind() != reflect.Struct && !isStructPtr(v) {
+					continue
 				}
+				w.enterElem(sf, name, strconv.Itoa(j), at, elem)
+				w.checkeRequiredFields(reflect.Indirect(v), elem)
+				w.leaveElem()
 			}
 		}
 	}
+}
 
-	return nil
+// fieldKeys возвращает для каждого поля ключ объекта n, в который оно разбиралось:
+// как и в encoding/json, ключ сравнивается с именем поля без учета регистра
+// и побеждает последний. Для полей без ключа возвращается имя поля.
+// Ключи лежат в общем стеке walker и действительны до вызова dropKeys(mark)
+func (w *walker) fieldKeys(n *node, fields *structFields) (keys []string, mark int) {
+	mark = len(w.names)
+	for i := range fields.list {
+		w.names = append(w.names, fields.list[i].name)
+	}
+	keys = w.names[mark:len(w.names):len(w.names)]
+	if n == nil || n.kind != nodeObject {
+		return keys, mark
+	}
+	for _, m := range n.members {
+		if i := fields.lookup(m.key); i >= 0 {
+			keys[i] = m.key
+		}
+	}
+	return keys, mark
+}
+
+// dropKeys возвращает в стек ключи, полученные из fieldKeys
+func (w *walker) dropKeys(mark int) {
+	w.names = w.names[:mark]
+}
+
+func isRequeredFieldNil(n *node, name string) bool {
+	return n.field(name) == nil
 }
-func isFieldRequered(tagStr string) bool {
-	return strings.Contains(tagStr, "required")
+
+// isStructPtr сообщает, указывает ли v на структуру
+func isStructPtr(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct
 }
 
-func isRequeredFieldNil(check reflect.Value, tagStr string) bool {
-	if check.IsValid() {
-		return !check.MapIndex(reflect.ValueOf(strings.Split(tagStr, ",")[0])).IsValid()
+// mapKey возвращает ключ отображения в том виде, в каком он записан в json
+func mapKey(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
 	}
-	return true
+	return fmt.Sprint(key.Interface())
+}
+
+// mapKeys ключи отображения m, отсортированные одним из проходов
+type mapKeys struct {
+	m    reflect.Value // держит отображение, чтобы его адрес не занял другое
+	keys []reflect.Value
+}
+
+// sortedKeys возвращает отсортированные ключи отображения m. Ключи сортируются
+// один раз за разбор и переиспользуются следующими проходами
+func (w *walker) sortedKeys(m reflect.Value) []reflect.Value {
+	if m.Len() == 0 {
+		return nil
+	}
+	key := seenKey{typ: m.Type(), ptr: m.Pointer()}
+	if c, ok := w.keys[key]; ok && len(c.keys) == m.Len() {
+		return c.keys
+	}
+	keys := sortedKeys(m)
+	w.keys[key] = mapKeys{m: m, keys: keys}
+	return keys
 }
 
-func setDefaultFields(target interface{}, m interface{}) error {
-	fields := reflect.ValueOf(target).Elem()
-	check := reflect.ValueOf(m)
+// sortedKeys возвращает отсортированные ключи отображения,
+// чтобы ошибки выводились в одном и том же порядке
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return mapKey(keys[i]) < mapKey(keys[j])
+	})
+	return keys
+}
 
-	for i := 0; i < fields.NumField(); i++ {
-		tagStr := fields.Type().Field(i).Tag.Get("default")
-		tagJSONStr := fields.Type().Field(i).Tag.Get("json")
+// setDefaultFields выставляет значения по умолчанию полям структуры v, ключей которых нет в n
+func (w *walker) setDefaultFields(v reflect.Value, n *node) {
+	fields := cachedFields(v.Type(), w.p.fieldTag)
+	keys, mark := w.fieldKeys(n, fields)
+	defer w.dropKeys(mark)
+	for i := 0; i < len(fields.list) && !w.done(); i++ {
+		fi := &fields.list[i]
+		sf := v.Type().FieldByIndex(fi.index)
+		name := keys[i]
+		tagStr, hasDefault := w.defaultValue(sf, name)
+		f, ok := fieldValue(v, fi.index)
+		if !ok {
+			// пустой встроенный указатель создается, только если есть что в него записать
+			if !hasDefault || !isRequeredFieldNil(n, name) {
+				continue
+			}
+			var err error
+			if f, err = fieldByIndex(v, fi.index); err != nil {
+				fe := w.fieldError(n, sf, name, KindDefault)
+				fe.Value = tagStr
+				fe.Err = err
+				w.fail(fe)
+				continue
+			}
+		}
 
-		if tagStr != "" && isRequeredFieldNil(check, tagJSONStr) {
-			switch fields.Field(i).Kind() {
-			case reflect.Int:
-				val, err := strconv.ParseInt(tagStr, 10, 32)
-				if err != nil {
-					return err
-				}
-				fields.Field(i).SetInt(val)
-			case reflect.Int8:
-				val, err := strconv.ParseInt(tagStr, 10, 8)
-				if err != nil {
-					return err
-				}
-				fields.Field(i).SetInt(val)
-			case reflect.Int16:
-				val, err := strconv.ParseInt(tagStr, 10, 16)
-				if err != nil {
-					return err
-				}
-				fields.Field(i).SetInt(val)
-			case reflect.Int32:
-				val, err := strconv.ParseInt(tagStr, 10, 32)
-				if err != nil {
-					return err
-				}
-				fields.Field(i).SetInt(val)
-			case reflect.Int64:
-				val, err := strconv.ParseInt(tagStr, 10, 64)
-				if err != nil {
-					return err
-				}
-				fields.Field(i).SetInt(val)
-			case reflect.Uint:
-				u, err := strconv.ParseUint(tagStr, 10, 32)
-				if err != nil {
-					return err
-				}
-				fields.Field(i).SetUint(u)
-			case reflect.Uint8:
-				u, err := strconv.ParseUint(tagStr, 10, 8)
-				if err != nil {
-					return err
-				}
-				fields.Field(i).SetUint(u)
-			case reflect.Uint16:
-				u, err := strconv.ParseUint(tagStr, 10, 16)
-				if err != nil {
-					return err
+		if hasDefault && isRequeredFieldNil(n, name) && !w.noAlloc(sf) {
+			err := w.p.setDefault(f, sf, tagStr)
+			if err != nil {
+				fe := w.fieldError(n, sf, name, KindDefault)
+				fe.Value = tagStr
+				fe.Err = err
+				w.fail(fe)
+				continue
+			}
+		}
+
+		switch f.Kind() {
+		case reflect.Struct:
+			w.enter(step{field: sf.Name, token: name, at: n.field(name)})
+			w.setDefaultFields(f, n.field(name))
+			w.leave()
+		case reflect.Ptr:
+			if f.Type().Elem().Kind() != reflect.Struct || isUnmarshaler(f.Type().Elem()) {
+				break
+			}
+			if f.IsNil() {
+				// явный null оставляет указатель пустым; тип, который уже создается выше
+				// по пути, не создается снова, иначе рекурсивные типы создавались бы бесконечно
+				if !w.p.allocStructs || !isRequeredFieldNil(n, name) || w.noAlloc(sf) || w.alloc[f.Type()] {
+					break
 				}
-				fields.Field(i).SetUint(u)
-			case reflect.Uint32:
-				u, err := strconv.ParseUint(tagStr, 10, 32)
-				if err != nil {
-					return err
+				f.Set(reflect.New(f.Type().Elem()))
+				if w.alloc == nil {
+					w.alloc = map[reflect.Type]bool{}
 				}
-				fields.Field(i).SetUint(u)
-			case reflect.Uint64:
-				u, err := strconv.ParseUint(tagStr, 10, 64)
-				if err != nil {
-					return err
+				w.alloc[f.Type()] = true
+				w.visit(f)
+				w.enter(step{field: sf.Name, token: name, at: n.field(name)})
+				w.setDefaultFields(f.Elem(), n.field(name))
+				w.leave()
+				delete(w.alloc, f.Type())
+				break
+			}
+			if !w.visit(f) {
+				break
+			}
+			w.enter(step{field: sf.Name, token: name, at: n.field(name)})
+			w.setDefaultFields(f.Elem(), n.field(name))
+			w.leave()
+		case reflect.Slice, reflect.Array:
+			for j := 0; j < f.Len() && !w.done(); j++ {
+				v := f.Index(j)
+				if v.Kind() == reflect.Struct || isStructPtr(v) && w.visit(v) {
+					elem := n.field(name).elem(j)
+					w.enterElem(sf, name, strconv.Itoa(j), n.field(name), elem)
+					w.setDefaultFields(reflect.Indirect(v), elem)
+					w.leaveElem()
 				}
-				fields.Field(i).SetUint(u)
-			case reflect.Float32:
-				f, err := strconv.ParseFloat(tagStr, 32)
-				if err != nil {
-					return err
+			}
+		case reflect.Map:
+			for _, key := range w.sortedKeys(f) {
+				if w.done() {
+					break
 				}
-				fields.Field(i).SetFloat(f)
-			case reflect.Float64:
-				f, err := strconv.ParseFloat(tagStr, 64)
-				if err != nil {
-					return err
+				v := f.MapIndex(key)
+				if isStructPtr(v) && w.visit(v) {
+					elem := n.field(name).field(mapKey(key))
+					w.enterElem(sf, name, mapKey(key), n.field(name), elem)
+					w.setDefaultFields(v.Elem(), elem)
+					w.leaveElem()
 				}
-				fields.Field(i).SetFloat(f)
-			case reflect.String:
-				fields.Field(i).SetString(tagStr)
-			default:
-				err := fmt.Sprintf(`type of field "%v" (type %v) is not support setting defaul value`, fields.Type().Field(i).Name, fields.Type().Field(i).Type)
-				return errors.New(err)
 			}
 		}
+	}
+}
 
-		switch fields.Field(i).Kind() {
-		case reflect.Struct:
-			if isRequeredFieldNil(check, tagJSONStr) {
-				err := setDefaultFields(fields.Field(i).Addr().Interface(), nil)
-				if err != nil {
-					return err
-				}
-			} else {
-				err := setDefaultFields(fields.Field(i).Addr().Interface(), check.MapIndex(reflect.ValueOf(strings.Split(tagJSONStr, ",")[0])).Interface())
-				if err != nil {
-					return err
-				}
-			}
+// visit отмечает указатель v пройденным и сообщает, что он встретился впервые.
+// Так циклы через указатели обходятся один раз
+func (w *walker) visit(v reflect.Value) bool {
+	if !recursive(v.Type().Elem()) {
+		return true
+	}
+	key := seenKey{typ: v.Type(), ptr: v.Pointer()}
+	if w.seen[key] {
+		return false
+	}
+	if w.seen == nil {
+		w.seen = map[seenKey]bool{}
+	}
+	w.seen[key] = true
+	return true
+}
 
-		case reflect.Slice:
-			for j := 0; j < fields.Field(i).Len(); j++ {
-				if (fields.Field(i).Index(j).Kind() == reflect.Struct ||
-					fields.Field(i).Index(j).Kind() == reflect.Ptr) && !fields.Field(i).Index(j).IsZero() {
-					switch fields.Field(i).Index(j).Kind() {
-					case reflect.Struct:
-						err := setDefaultFields(fields.Field(i).Index(j).Addr().Interface(), reflect.ValueOf(check.MapIndex(reflect.ValueOf(strings.Split(tagJSONStr, ",")[0])).Interface()).Index(j).Interface())
-						if err != nil {
-							return err
-						}
-					case reflect.Ptr:
-						err := setDefaultFields(fields.Field(i).Index(j).Interface(), reflect.ValueOf(check.MapIndex(reflect.ValueOf(strings.Split(tagJSONStr, ",")[0])).Interface()).Index(j).Interface())
-						if err != nil {
-							return err
-						}
-					}
+var recursiveCache sync.Map // reflect.Type -> bool
 
-				}
+// recursive сообщает, может ли значение типа t через указатели, срезы, отображения
+// или интерфейсы ссылаться на значение того же типа. Только такие указатели могут
+// образовать цикл, поэтому остальные visit не запоминает
+func recursive(t reflect.Type) bool {
+	if r, ok := recursiveCache.Load(t); ok {
+		return r.(bool)
+	}
+	r := reaches(t, t, map[reflect.Type]bool{})
+	recursiveCache.Store(t, r)
+	return r
+}
+
+// reaches сообщает, достижим ли тип target из составляющих типа t
+func reaches(t, target reflect.Type, seen map[reflect.Type]bool) bool {
+	var elems []reflect.Type
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		elems = append(elems, t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			elems = append(elems, t.Field(i).Type)
+		}
+	}
+	for _, e := range elems {
+		if e == target {
+			return true
+		}
+		if !seen[e] {
+			seen[e] = true
+			if reaches(e, target, seen) {
+				return true
 			}
-		case reflect.Map:
-			for _, key := range fields.Field(i).MapKeys() {
-				if fields.Field(i).MapIndex(key).Kind() == reflect.Ptr && !fields.Field(i).MapIndex(key).IsZero() {
-					if isRequeredFieldNil(check, tagJSONStr) {
-						err := setDefaultFields(fields.Field(i).MapIndex(key).Interface(), nil)
-						if err != nil {
-							return err
-						}
-					} else {
-						err := setDefaultFields(fields.Field(i).MapIndex(key).Interface(), reflect.ValueOf(check.MapIndex(reflect.ValueOf(strings.Split(tagJSONStr, ",")[0])).Interface()).MapIndex(key).Interface())
-						if err != nil {
-							return err
-						}
-					}
+		}
+	}
+	return false
+}
 
-				}
+// noAlloc сообщает, что указатель на структуру помечен default:"-" и не создается WithAllocStructs
+func (w *walker) noAlloc(sf reflect.StructField) bool {
+	return sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct &&
+		sf.Tag.Get(w.p.defaultTag) == "-"
+}
+
+// defaultValue возвращает значение по умолчанию для поля sf: от первого ответившего
+// DefaultProvider, а если таких нет — из тэга
+func (w *walker) defaultValue(sf reflect.StructField, name string) (string, bool) {
+	if len(w.p.defaults) > 0 {
+		_, path := stepPaths(append(w.path, step{field: sf.Name, token: name}))
+		for _, provide := range w.p.defaults {
+			if v, ok := provide(path, sf); ok {
+				return v, true
 			}
 		}
+	}
+
+	tagStr := sf.Tag.Get(w.p.defaultTag)
+	return tagStr, tagStr != ""
+}
 
+// setDefault записывает значение по умолчанию в поле, для указателя создает новое значение.
+// Срезы, массивы и отображения задаются литералом json или списком через разделитель
+// WithDefaultSeparator: "a,b,c" или "k1:1,k2:2". Длина массива должна совпадать точно
+func (p *Parser) setDefault(field reflect.Value, sf reflect.StructField, tagStr string) error {
+	if isUnmarshaler(field.Type()) {
+		return setDefaultValue(field, sf, tagStr)
 	}
 
-	return nil
+	switch field.Kind() {
+	case reflect.Ptr:
+		v := reflect.New(field.Type().Elem())
+		if err := p.setDefault(v.Elem(), sf, tagStr); err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	case reflect.Slice:
+		if strings.HasPrefix(strings.TrimSpace(tagStr), "[") {
+			return p.setDefaultJSON(field, sf, tagStr)
+		}
+		parts := strings.Split(tagStr, p.separator)
+		s := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setDefaultValue(s.Index(i), sf, part); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		field.Set(s)
+		return nil
+	case reflect.Array:
+		s := reflect.New(reflect.SliceOf(field.Type().Elem())).Elem()
+		if err := p.setDefault(s, sf, tagStr); err != nil {
+			return err
+		}
+		if s.Len() != field.Len() {
+			return fmt.Errorf("default has %d elements, want %d", s.Len(), field.Len())
+		}
+		reflect.Copy(field, s)
+		return nil
+	case reflect.Map:
+		if strings.HasPrefix(strings.TrimSpace(tagStr), "{") {
+			return p.setDefaultJSON(field, sf, tagStr)
+		}
+		t := field.Type()
+		m := reflect.MakeMap(t)
+		for _, part := range strings.Split(tagStr, p.separator) {
+			k, v, ok := strings.Cut(part, ":")
+			if !ok {
+				return fmt.Errorf("missing ':' in map element %q", part)
+			}
+			key := reflect.New(t.Key()).Elem()
+			if err := setDefaultValue(key, sf, k); err != nil {
+				return fmt.Errorf("key %q: %w", k, err)
+			}
+			val := reflect.New(t.Elem()).Elem()
+			if err := setDefaultValue(val, sf, v); err != nil {
+				return fmt.Errorf("element %q: %w", k, err)
+			}
+			m.SetMapIndex(key, val)
+		}
+		field.Set(m)
+		return nil
+	}
+
+	return setDefaultValue(field, sf, tagStr)
 }
 
-func readJSON(filepath string, target interface{}) error {
-	f, err := os.Open(filepath)
+// setDefaultJSON разбирает значение по умолчанию, заданное литералом json, тем же
+// декодером, что и документ: Duration строкой, тэг layout поля sf для time.Time
+func (p *Parser) setDefaultJSON(field reflect.Value, sf reflect.StructField, tagStr string) error {
+	data := []byte(tagStr)
+	n, err := parseTree(data)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	b := bufio.NewReader(f)
-	d := json.NewDecoder(b)
-	d.DisallowUnknownFields()
-	err = d.Decode(target)
+	v := reflect.New(field.Type()).Elem()
+	d := decodeState{p: p, data: data, warn: p.onUnknownField}
+	if layout := sf.Tag.Get(layoutTag); layout != "" {
+		err = d.time(v, n, layout)
+	} else {
+		err = d.value(v, n)
+	}
 	if err != nil {
 		return err
 	}
+	field.Set(v)
+	return nil
+}
+
+// textUnmarshaler возвращает encoding.TextUnmarshaler поля или его адреса.
+// Нулевой указатель при этом создается
+func textUnmarshaler(field reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if field.Kind() == reflect.Ptr && field.Type().Implements(textUnmarshalerType) {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return field.Interface().(encoding.TextUnmarshaler), true
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler), true
+	}
+	return nil, false
+}
+
+// setDefaultValue разбирает значение тэга default и записывает его в поле
+func setDefaultValue(field reflect.Value, sf reflect.StructField, tagStr string) error {
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(tagStr)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		layout := sf.Tag.Get(layoutTag)
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, tagStr)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if u, ok := textUnmarshaler(field); ok {
+		return u.UnmarshalText([]byte(tagStr))
+	}
+
+	switch field.Kind() {
+	case reflect.Int:
+		val, err := strconv.ParseInt(tagStr, 10, 32)
+		if err != nil {
+			return err
+		}
+		field.SetInt(val)
+	case reflect.Int8:
+		val, err := strconv.ParseInt(tagStr, 10, 8)
+		if err != nil {
+			return err
+		}
+		field.SetInt(val)
+	case reflect.Int16:
+		val, err := strconv.ParseInt(tagStr, 10, 16)
+		if err != nil {
+			return err
+		}
+		field.SetInt(val)
+	case reflect.Int32:
+		val, err := strconv.ParseInt(tagStr, 10, 32)
+		if err != nil {
+			return err
+		}
+		field.SetInt(val)
+	case reflect.Int64:
+		val, err := strconv.ParseInt(tagStr, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(val)
+	case reflect.Uint:
+		u, err := strconv.ParseUint(tagStr, 10, 32)
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Uint8:
+		u, err := strconv.ParseUint(tagStr, 10, 8)
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Uint16:
+		u, err := strconv.ParseUint(tagStr, 10, 16)
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Uint32:
+		u, err := strconv.ParseUint(tagStr, 10, 32)
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Uint64:
+		u, err := strconv.ParseUint(tagStr, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32:
+		f, err := strconv.ParseFloat(tagStr, 32)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(tagStr, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.String:
+		field.SetString(tagStr)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(tagStr)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		err := fmt.Sprintf(`type %v is not support setting defaul value`, sf.Type)
+		return errors.New(err)
+	}
 
 	return nil
 }
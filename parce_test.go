@@ -1,9 +1,185 @@
 package testparcer
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
+// testFS содержит тестовые json файлы
+var testFS = fstest.MapFS{
+	"test1.json": {Data: []byte(`{
+    "byte-field": 10,
+    "string-field": "foo",
+    "int1-field": 456,
+    "int2-field": -789,
+    "slice-field": null,
+    "struct-field": {
+        "byte-field": 10,
+        "string-field": "foo",
+        "int1-field": 456
+    },
+    "primitive-map-field": {
+        "foo": "bar",
+        "baz": "foo"
+    },
+    "struct-map-field": {
+        "foo": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        },
+        "bar": null,
+        "baz": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        }
+    }
+}`)},
+	"test2.json": {Data: []byte(`{
+    "byte-field": 10,
+    "string-field": "foo",
+    "int1-field": 456,
+    "int2-field": -789,
+    "slice-field": null,
+    "struct-field": {
+        "byte-field": 10,
+        "string-field": "foo",
+        "int1-field": 456
+    },
+    "primitive-map-field": {
+        "foo": 1,
+        "baz": 2
+    },
+    "struct-map-field": {
+        "foo": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        },
+        "bar": null,
+        "baz": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        }
+    }
+}`)},
+	"test3.json": {Data: []byte(`{
+    "byte-field": 10,
+    "string-field": "foo",
+    "int1-field": 0,
+    "int2-field": -789,
+    "slice-field": [
+        "foo",
+        "bar"
+    ],
+    "struct-field": {
+        "byte-field": 10,
+        "string-field": "foo",
+        "int1-field": 456
+    },
+    "primitive-map-field": {
+        "foo": 1,
+        "baz": 2
+    },
+    "struct-map-field": {
+        "foo": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        },
+        "bar": null,
+        "baz": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        }
+    }
+}`)},
+	"test4.json": {Data: []byte(`{
+    "byte-field": 10,
+    "string-field": "foo",
+    "int1-field": 456,
+    "slice-field": [
+        "foo",
+        "bar"
+    ],
+    "struct-field": {
+        "byte-field": 10,
+        "string-field": "foo",
+        "int1-field": 456
+    },
+    "primitive-map-field": {
+        "foo": 1,
+        "baz": 2
+    },
+    "struct-map-field": {
+        "foo": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        },
+        "bar": null,
+        "baz": {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        }
+    }
+}`)},
+	"test5.json": {Data: []byte(`{
+    "slice-field": [
+        {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        },
+        {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        },
+        {
+            "byte-field": 10,
+            "string-field": "foo",
+            "int1-field": 456
+        }
+    ]
+}`)},
+}
+
+// writeTestFile копирует файл из testFS во временный каталог и возвращает путь к нему
+func writeTestFile(t *testing.T, name string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, testFS[name].Data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// mustTree строит дерево node из значения, как если бы оно было прочитано из файла
+func mustTree(v interface{}) *node {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	n, err := parseTree(data)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
 type testDefaultStruct struct {
 	Name string `json:"name,required"`
 	Age  int    `json:"age" default:"18"`
@@ -301,7 +477,9 @@ func Test_checkeRequiredFields(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := checkeRequiredFields(tt.args.target, tt.args.m); (err != nil) != tt.wantErr {
+			w := defaultParser.newWalker("", nil, nil)
+			w.checkeRequiredFields(reflect.ValueOf(tt.args.target).Elem(), mustTree(tt.args.m))
+			if err := w.error(); (err != nil) != tt.wantErr {
 				t.Errorf("checkeRequiredFields() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -327,7 +505,7 @@ type testStructDefaultFieldsWrongByte struct {
 }
 
 type testStructDefaultFieldsWrongArr struct {
-	F1 []int `default:"10"`
+	F1 []int `default:"10,a"`
 }
 
 type testStructDefaultFieldsWrongMap struct {
@@ -428,7 +606,9 @@ func Test_setDefaultFields(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := setDefaultFields(tt.args.target, tt.args.m); (err != nil) != tt.wantErr {
+			w := defaultParser.newWalker("", nil, nil)
+			w.setDefaultFields(reflect.ValueOf(tt.args.target).Elem(), mustTree(tt.args.m))
+			if err := w.error(); (err != nil) != tt.wantErr {
 				t.Errorf("setDefaultFields() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -436,12 +616,548 @@ func Test_setDefaultFields(t *testing.T) {
 	}
 }
 
+func Test_setDefaultValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   interface{}
+		tag     string
+		want    interface{}
+		wantErr bool
+	}{
+		{"int", new(int), "-5", -5, false},
+		{"int8 overflow", new(int8), "300", int8(0), true},
+		{"uint", new(uint), "5", uint(5), false},
+		{"float", new(float64), "1.5", 1.5, false},
+		{"string", new(string), "str", "str", false},
+		{"bool true", new(bool), "true", true, false},
+		{"bool 1", new(bool), "1", true, false},
+		{"bool false", new(bool), "false", false, false},
+		{"bool wrong", new(bool), "yes", false, true},
+		{"duration", new(time.Duration), "1m30s", 90 * time.Second, false},
+		{"duration wrong", new(time.Duration), "90", time.Duration(0), true},
+		{"time", new(time.Time), "2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false},
+		{"time wrong", new(time.Time), "2024-01-02", time.Time{}, true},
+		{"unsupported", new(chan int), "1", (chan int)(nil), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := reflect.ValueOf(tt.field).Elem()
+			err := setDefaultValue(v, reflect.StructField{Name: "F", Type: v.Type()}, tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setDefaultValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := v.Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("setDefaultValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testStructDefaultBool struct {
+	Enabled bool `json:"enabled" default:"true"`
+	Debug   bool `json:"debug" default:"false"`
+}
+
+func TestParceBytes_bool(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want testStructDefaultBool
+	}{
+		{"absent", `{}`, testStructDefaultBool{Enabled: true}},
+		{"explicit false", `{"enabled": false}`, testStructDefaultBool{}},
+		{"explicit true", `{"debug": true}`, testStructDefaultBool{Enabled: true, Debug: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testStructDefaultBool{}
+			if err := ParceBytes([]byte(tt.data), &got); err != nil {
+				t.Fatalf("ParceBytes() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParceBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testStructTime struct {
+	Timeout  time.Duration `json:"timeout" default:"30s"`
+	Interval time.Duration `json:"interval"`
+	Start    time.Time     `json:"start" default:"2024-01-02T03:04:05Z"`
+	Day      time.Time     `json:"day" layout:"2006-01-02" default:"2024-01-02"`
+	DayPtr   *time.Time    `json:"day-ptr" layout:"2006-01-02"`
+}
+
+func TestParceBytes_time(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		data    string
+		want    testStructTime
+		wantErr bool
+	}{
+		{"defaults", `{}`, testStructTime{Timeout: 30 * time.Second, Start: start, Day: day}, false},
+		{
+			"values",
+			`{"timeout": "1h", "interval": 1000, "start": "2020-05-06T07:08:09Z", "day": "2021-03-04", "day-ptr": "2024-01-02"}`,
+			testStructTime{
+				Timeout:  time.Hour,
+				Interval: time.Microsecond,
+				Start:    time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC),
+				Day:      time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+				DayPtr:   &day,
+			},
+			false,
+		},
+		{"wrong duration", `{"timeout": "soon"}`, testStructTime{}, true},
+		{"wrong layout", `{"day": "2021-03-04T00:00:00Z"}`, testStructTime{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testStructTime{}
+			err := ParceBytes([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParceBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testLevel уровень логирования, задается строкой через UnmarshalText
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 1
+	case "info":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type testStructDefaultText struct {
+	Level    testLevel  `json:"level" default:"info"`
+	LevelPtr *testLevel `json:"level-ptr" default:"debug"`
+	Addr     net.IP     `json:"addr" default:"127.0.0.1"`
+}
+
+func TestParceBytes_textUnmarshaler(t *testing.T) {
+	debug := testLevel(1)
+	tests := []struct {
+		name    string
+		data    string
+		target  interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			"defaults",
+			`{}`,
+			&testStructDefaultText{},
+			&testStructDefaultText{Level: 2, LevelPtr: &debug, Addr: net.IPv4(127, 0, 0, 1)},
+			false,
+		},
+		{
+			"values",
+			`{"level": "debug", "addr": "10.0.0.1"}`,
+			&testStructDefaultText{},
+			&testStructDefaultText{Level: 1, LevelPtr: &debug, Addr: net.IPv4(10, 0, 0, 1)},
+			false,
+		},
+		{
+			"wrong default",
+			`{}`,
+			&struct {
+				Level testLevel `json:"level" default:"trace"`
+			}{},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.target, tt.want) {
+				t.Errorf("ParceBytes() = %+v, want %+v", tt.target, tt.want)
+			}
+		})
+	}
+}
+
+type testStructDefaultList struct {
+	Origins []string          `json:"origins" default:"a,b,c"`
+	Ports   []int             `json:"ports" default:"[80, 443]"`
+	Waits   []time.Duration   `json:"waits" default:"1s,1m"`
+	Limits  map[string]int    `json:"limits" default:"cpu:2,mem:512"`
+	Labels  map[string]string `json:"labels" default:"{\"k\":\"v\"}"`
+}
+
+func TestParceBytes_defaultList(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		opts    []Option
+		target  interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			"defaults",
+			`{}`,
+			nil,
+			&testStructDefaultList{},
+			&testStructDefaultList{
+				Origins: []string{"a", "b", "c"},
+				Ports:   []int{80, 443},
+				Waits:   []time.Duration{time.Second, time.Minute},
+				Limits:  map[string]int{"cpu": 2, "mem": 512},
+				Labels:  map[string]string{"k": "v"},
+			},
+			false,
+		},
+		{
+			"values",
+			`{"origins": [], "limits": {"cpu": 1}}`,
+			nil,
+			&testStructDefaultList{},
+			&testStructDefaultList{
+				Origins: []string{},
+				Ports:   []int{80, 443},
+				Waits:   []time.Duration{time.Second, time.Minute},
+				Limits:  map[string]int{"cpu": 1},
+				Labels:  map[string]string{"k": "v"},
+			},
+			false,
+		},
+		{
+			"separator",
+			`{}`,
+			[]Option{WithDefaultSeparator(";")},
+			&struct {
+				Hosts []string `json:"hosts" default:"a,b;c"`
+			}{},
+			&struct {
+				Hosts []string `json:"hosts" default:"a,b;c"`
+			}{Hosts: []string{"a,b", "c"}},
+			false,
+		},
+		{
+			"json literal with durations",
+			`{}`,
+			nil,
+			&struct {
+				Waits []time.Duration `json:"waits" default:"[\"1s\", \"2s\"]"`
+			}{},
+			&struct {
+				Waits []time.Duration `json:"waits" default:"[\"1s\", \"2s\"]"`
+			}{Waits: []time.Duration{time.Second, 2 * time.Second}},
+			false,
+		},
+		{
+			"json literal with layout",
+			`{}`,
+			nil,
+			&struct {
+				Days []time.Time `json:"days" default:"[\"2024-01-02\"]" layout:"2006-01-02"`
+			}{},
+			&struct {
+				Days []time.Time `json:"days" default:"[\"2024-01-02\"]" layout:"2006-01-02"`
+			}{Days: []time.Time{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}},
+			false,
+		},
+		{
+			"wrong element",
+			`{}`,
+			nil,
+			&struct {
+				Ports []int `json:"ports" default:"80,http"`
+			}{},
+			nil,
+			true,
+		},
+		{
+			"wrong map element",
+			`{}`,
+			nil,
+			&struct {
+				Limits map[string]int `json:"limits" default:"cpu"`
+			}{},
+			nil,
+			true,
+		},
+		{
+			"wrong literal",
+			`{}`,
+			nil,
+			&struct {
+				Ports []int `json:"ports" default:"[80,"`
+			}{},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), tt.target, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.target, tt.want) {
+				t.Errorf("ParceBytes() = %+v, want %+v", tt.target, tt.want)
+			}
+		})
+	}
+}
+
+type testStructPointers struct {
+	Port    *int      `json:"port" default:"8080"`
+	Name    *string   `json:"name,required"`
+	Tags    *[]string `json:"tags" default:"a,b"`
+	Level   *int      `json:"level"`
+	Timeout **int     `json:"timeout" default:"5"`
+}
+
+func TestParceBytes_pointers(t *testing.T) {
+	port, name, zero, five := 8080, "srv", 0, 5
+	fivePtr := &five
+	tests := []struct {
+		name    string
+		data    string
+		want    testStructPointers
+		wantErr bool
+	}{
+		{
+			"defaults",
+			`{"name": "srv"}`,
+			testStructPointers{Port: &port, Name: &name, Tags: &[]string{"a", "b"}, Timeout: &fivePtr},
+			false,
+		},
+		{
+			"explicit null",
+			`{"port": null, "name": null, "tags": null, "timeout": null}`,
+			testStructPointers{},
+			false,
+		},
+		{
+			"explicit zero",
+			`{"port": 0, "name": "srv", "level": 0, "tags": []}`,
+			testStructPointers{Port: &zero, Name: &name, Tags: &[]string{}, Level: &zero, Timeout: &fivePtr},
+			false,
+		},
+		{"missing required", `{}`, testStructPointers{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testStructPointers{}
+			err := ParceBytes([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParceBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testBaseConfig struct {
+	Service string `json:"service,required"`
+	Level   string `json:"level" default:"info"`
+}
+
+type EmbeddedLimits struct {
+	Limit int `json:"limit" default:"10"`
+}
+
+type testStructEmbedded struct {
+	testBaseConfig
+	*EmbeddedLimits
+	Name   string         `json:"name" default:"app"`
+	Nested testBaseConfig `json:"nested"`
+}
+
+func TestParceBytes_embedded(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    testStructEmbedded
+		wantErr bool
+	}{
+		{
+			"promoted defaults",
+			`{"service": "api"}`,
+			testStructEmbedded{
+				testBaseConfig: testBaseConfig{Service: "api", Level: "info"},
+				EmbeddedLimits: &EmbeddedLimits{Limit: 10},
+				Name:           "app",
+				Nested:         testBaseConfig{Level: "info"},
+			},
+			false,
+		},
+		{
+			"promoted values",
+			`{"service": "api", "level": "debug", "limit": 5, "nested": {"service": "db"}}`,
+			testStructEmbedded{
+				testBaseConfig: testBaseConfig{Service: "api", Level: "debug"},
+				EmbeddedLimits: &EmbeddedLimits{Limit: 5},
+				Name:           "app",
+				Nested:         testBaseConfig{Service: "db", Level: "info"},
+			},
+			false,
+		},
+		{"missing promoted required", `{"level": "debug"}`, testStructEmbedded{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testStructEmbedded{}
+			err := ParceBytes([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParceBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParceBytes_embeddedError(t *testing.T) {
+	err := ParceBytes([]byte(`{"level": "debug"}`), &testStructEmbedded{})
+
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("ParceBytes() error = %v, want *FieldError", err)
+	}
+	if fe.Field != "Service" || fe.Path != "/service" || fe.Kind != KindRequired {
+		t.Errorf("ParceBytes() error = %+v", *fe)
+	}
+}
+
+type testStructUntagged struct {
+	Host    string `default:"localhost"`
+	Port    int    `json:",required"`
+	Secret  string `json:"-" default:"secret"`
+	Timeout int    `json:"timeout" default:"30"`
+}
+
+func TestParceBytes_untagged(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    testStructUntagged
+		wantErr bool
+	}{
+		{"go names", `{"Port": 1}`, testStructUntagged{Host: "localhost", Port: 1, Timeout: 30}, false},
+		{"case insensitive", `{"port": 1, "HOST": "db", "TimeOut": 5}`, testStructUntagged{Host: "db", Port: 1, Timeout: 5}, false},
+		{"missing required", `{"host": "db"}`, testStructUntagged{}, true},
+		{"skipped field", `{"Port": 1, "-": "x"}`, testStructUntagged{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testStructUntagged{}
+			err := ParceBytes([]byte(tt.data), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParceBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParceBytes_caseInsensitivePath(t *testing.T) {
+	err := ParceBytes([]byte(`{"service": "api", "Nested": {"LEVEL": "debug"}}`), &testStructEmbedded{})
+
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("ParceBytes() error = %v, want *FieldError", err)
+	}
+	if fe.Field != "Nested.Service" || fe.Path != "/Nested/service" || fe.Kind != KindRequired {
+		t.Errorf("ParceBytes() error = %+v", *fe)
+	}
+}
+
+type testEndpoint struct {
+	Host string `json:"host,required"`
+	Port int    `json:"port" default:"80"`
+}
+
+type testStructArray struct {
+	Endpoints [2]testEndpoint  `json:"endpoints,required"`
+	Backups   [2]*testEndpoint `json:"backups"`
+	Weights   [3]int           `json:"weights" default:"1,2,3"`
+	Zones     [2]string        `json:"zones" default:"[\"a\",\"b\"]"`
+}
+
+func TestParceBytes_array(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    testStructArray
+		wantErr Kind
+	}{
+		{
+			"defaults",
+			`{"endpoints": [{"host": "a"}, {"host": "b", "port": 8080}], "backups": [null, {"host": "c"}]}`,
+			testStructArray{
+				Endpoints: [2]testEndpoint{{"a", 80}, {"b", 8080}},
+				Backups:   [2]*testEndpoint{nil, {"c", 80}},
+				Weights:   [3]int{1, 2, 3},
+				Zones:     [2]string{"a", "b"},
+			},
+			"",
+		},
+		{"missing required", `{}`, testStructArray{}, KindRequired},
+		{"missing required in element", `{"endpoints": [{"host": "a"}, {}]}`, testStructArray{}, KindRequired},
+		{"short", `{"endpoints": [{"host": "a"}]}`, testStructArray{}, KindLength},
+		{"long", `{"endpoints": [{"host": "a"}, {"host": "b"}], "weights": [1, 2, 3, 4]}`, testStructArray{}, KindLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testStructArray{}
+			err := ParceBytes([]byte(tt.data), &got)
+
+			var fe *FieldError
+			if errors.As(err, &fe) != (tt.wantErr != "") || fe != nil && fe.Kind != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, want kind %q", err, tt.wantErr)
+			}
+			if tt.wantErr == "" && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParceBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParceBytes_arrayDefaultLength(t *testing.T) {
+	target := &struct {
+		Weights [3]int `json:"weights" default:"1,2"`
+	}{}
+	err := ParceBytes([]byte(`{}`), target)
+
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Kind != KindDefault {
+		t.Errorf("ParceBytes() error = %v, want default error", err)
+	}
+}
+
 type testStruct struct {
 	F1 byte                           `json:"byte-field"`                           // обычное поле без новых тэгов; опциональное, так как нет ключа required
 	F2 string                         `json:"string-field,required"`                // строковое поле; обязательное, так как есть ключ required
 	F3 int                            `json:"int1-field" default:"123"`             // числовое поле со значением по умолчанию 123
 	F4 int                            `json:"int2-field" default:"foo"`             // числовое поле со значением по умолчанию foo (ошибка, некорректное значение)
-	F5 []string                       `json:"slice-field,required" default:"what?"` // обязательное поле со значением по умолчанию (ошибка, срезы не могут иметь default)
+	F5 []string                       `json:"slice-field,required" default:"what?"` // обязательное поле со значением по умолчанию (default не применяется, так как поле обязательное)
 	F6 anotherTestStruct1             `json:"struct-field,required"`                // подструктура, которая тоже может иметь тэги и т.д.
 	F7 map[string]int                 `json:"primitive-map-field"`                  // обычное отображение без новых тэгов
 	F8 map[string]*anotherTestStruct2 `json:"struct-map-field,required"`            // отображение на указатели подструктур, которые тоже могут иметь тэги и т.д.
@@ -528,7 +1244,7 @@ func TestParce(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := Parce(tt.args.filepath, tt.args.target)
+			err := Parce(writeTestFile(t, tt.args.filepath), tt.args.target)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Parce() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -536,7 +1252,52 @@ func TestParce(t *testing.T) {
 	}
 }
 
-func Test_readJSON(t *testing.T) {
+func TestParceFS(t *testing.T) {
+	type args struct {
+		name   string
+		target interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"test_2 required field F5",
+			args{
+				name:   "test2.json",
+				target: &testStruct{},
+			},
+			true,
+		},
+		{
+			"test_3",
+			args{
+				name:   "test3.json",
+				target: &testStruct{},
+			},
+			false,
+		},
+		{
+			"missing file",
+			args{
+				name:   "missing.json",
+				target: &testStruct{},
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceFS(testFS, tt.args.name, tt.args.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParceFS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParceReader(t *testing.T) {
 	type args struct {
 		filepath string
 		target   interface{}
@@ -554,14 +1315,199 @@ func Test_readJSON(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"test_5",
+			args{
+				filepath: "test5.json",
+				target:   &anotherTestStruct3{},
+			},
+			false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := readJSON(tt.args.filepath, tt.args.target)
+			f, err := testFS.Open(tt.args.filepath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			err = ParceReader(f, tt.args.target)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("readJSON() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParceReader() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 		})
 	}
 }
+
+func TestParceBytes(t *testing.T) {
+	type args struct {
+		data   string
+		target interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			"required and default",
+			args{
+				data:   `{"name": "jin"}`,
+				target: &testDefaultStruct{},
+			},
+			false,
+		},
+		{
+			"missing required",
+			args{
+				data:   `{"age": 20}`,
+				target: &testDefaultStruct{},
+			},
+			true,
+		},
+		{
+			"syntax error",
+			args{
+				data:   `{"name": }`,
+				target: &testDefaultStruct{},
+			},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.args.data), tt.args.target)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeBenchConfig пишет конфиг размером в несколько мегабайт
+func writeBenchConfig(b *testing.B) string {
+	var sb strings.Builder
+	sb.WriteString(`{"byte-field": 10, "string-field": "foo", "int1-field": 1, "int2-field": 2,`)
+	sb.WriteString(`"slice-field": ["foo", "bar"], "struct-field": {"string-field": "foo"},`)
+	sb.WriteString(`"primitive-map-field": {"foo": 1}, "struct-map-field": {`)
+	for i := 0; i < 20000; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `"key-%d": {"byte-field": 10, "string-field": "value %d", "int1-field": %d}`, i, i, i)
+	}
+	sb.WriteString("}}")
+
+	path := filepath.Join(b.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// Benchmark_baselineParce — первая версия Parce, с которой сравнивается BenchmarkParce
+func Benchmark_baselineParce(b *testing.B) {
+	path := writeBenchConfig(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := baselineParce(path, &testStruct{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParce(b *testing.B) {
+	path := writeBenchConfig(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Parce(path, &testStruct{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParceBytes_target(t *testing.T) {
+	var nilStruct *testDefaultStruct
+	var ptr *testDefaultStruct
+
+	tests := []struct {
+		name   string
+		target interface{}
+	}{
+		{"nil", nil},
+		{"not a pointer", testDefaultStruct{}},
+		{"nil pointer", nilStruct},
+		{"pointer to map", &map[string]interface{}{}},
+		{"pointer to pointer", &ptr},
+		{"pointer to slice of strings", &[]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(`{"name": "jin"}`), tt.target)
+			if !errors.Is(err, ErrorUnsupportedTarget) {
+				t.Errorf("ParceBytes() error = %v, want %v", err, ErrorUnsupportedTarget)
+			}
+		})
+	}
+}
+
+func TestParceBytes_slice(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		target  interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			"slice of structs",
+			`[{"name": "jin"}, {"name": "jo", "age": 30}]`,
+			&[]testDefaultStruct{},
+			&[]testDefaultStruct{{Name: "jin", Age: 18}, {Name: "jo", Age: 30}},
+			false,
+		},
+		{
+			"slice of pointers",
+			`[{"name": "jin"}, null]`,
+			&[]*testDefaultStruct{},
+			&[]*testDefaultStruct{{Name: "jin", Age: 18}, nil},
+			false,
+		},
+		{
+			"array",
+			`[{"name": "jin"}]`,
+			&[1]testDefaultStruct{},
+			&[1]testDefaultStruct{{Name: "jin", Age: 18}},
+			false,
+		},
+		{
+			"missing required in element",
+			`[{"name": "jin"}, {"age": 30}]`,
+			&[]testDefaultStruct{},
+			nil,
+			true,
+		},
+		{
+			"array length mismatch",
+			`[{"name": "jin"}]`,
+			&[2]testDefaultStruct{},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil && !reflect.DeepEqual(tt.target, tt.want) {
+				t.Errorf("ParceBytes() target = %+v, want %+v", tt.target, tt.want)
+			}
+		})
+	}
+}
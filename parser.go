@@ -0,0 +1,350 @@
+package testparcer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Parser разбирает json с проверкой обязательных полей и значениями по умолчанию.
+// Настраивается опциями в NewParser и после этого не меняется, поэтому один Parser
+// можно использовать из нескольких горутин
+type Parser struct {
+	fieldTag       string
+	defaultTag     string
+	ruleTag        string
+	separator      string
+	allocStructs   bool
+	allErrors      bool
+	unknownFields  UnknownFieldPolicy
+	onUnknownField func(*FieldError)
+	validators     []func(target interface{}) error
+	defaults       []DefaultProvider
+	customRules    map[string]RuleFunc
+	errs           []error // ошибки опций, их возвращает каждый разбор
+
+	rules   sync.Map // reflect.Type -> *typeRules
+	checked sync.Map // reflect.Type -> error проверки тэгов цели
+}
+
+// Option настраивает Parser
+type Option func(*Parser)
+
+// DefaultProvider возвращает значение по умолчанию для поля field, ключа которого нет в json.
+// path — JSON Pointer до ключа. Значение разбирается так же, как текст тэга default
+type DefaultProvider func(path string, field reflect.StructField) (string, bool)
+
+// RuleFunc проверяет значение поля с правилом из тэга, например parcer:"topic" или parcer:"prefix=app".
+// value — значение поля, указатели разыменованы; path — JSON Pointer до значения;
+// param — параметр правила после "=" или пустая строка
+type RuleFunc func(value interface{}, path string, param string) error
+
+var defaultParser = NewParser()
+
+// NewParser создает Parser с опциями opts
+func NewParser(opts ...Option) *Parser {
+	p := &Parser{
+		fieldTag:   "json",
+		defaultTag: "default",
+		ruleTag:    "parcer",
+		separator:  ",",
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// parserFor возвращает Parser по умолчанию, если опций нет, иначе новый
+func parserFor(opts []Option) *Parser {
+	if len(opts) == 0 {
+		return defaultParser
+	}
+	return NewParser(opts...)
+}
+
+// Parce разбирает файл filepath в target
+func (p *Parser) Parce(filepath string, target interface{}) error {
+	// ReadFile выделяет буфер сразу по размеру файла, без роста, как в io.ReadAll
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		err := fmt.Errorf("%w: %v", ErrorWhileReadingFile, err)
+		return err
+	}
+
+	return p.parce(filepath, data, target)
+}
+
+// ParceReader разбирает json из r в target.
+// Если у r есть метод Name, как у *os.File, имя попадает в позиции ошибок
+func (p *Parser) ParceReader(r io.Reader, target interface{}) error {
+	data, err := readAll(r)
+	if err != nil {
+		err := fmt.Errorf("%w: %v", ErrorWhileReadingFile, err)
+		return err
+	}
+
+	var name string
+	if f, ok := r.(interface{ Name() string }); ok {
+		name = f.Name()
+	}
+
+	return p.parce(name, data, target)
+}
+
+// readAll читает r целиком. Если размер известен заранее, как у *os.File или
+// *bytes.Reader, буфер выделяется один раз вместо роста в io.ReadAll
+func readAll(r io.Reader) ([]byte, error) {
+	size := -1
+	switch s := r.(type) {
+	case interface{ Len() int }:
+		size = s.Len()
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if fi, err := s.Stat(); err == nil && fi.Mode().IsRegular() {
+			size = int(fi.Size())
+		}
+	}
+	if size < 0 {
+		return io.ReadAll(r)
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(size + bytes.MinRead)
+	_, err := buf.ReadFrom(r)
+	return buf.Bytes(), err
+}
+
+// ParceFS разбирает файл name из fsys в target
+func (p *Parser) ParceFS(fsys fs.FS, name string, target interface{}) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		err := fmt.Errorf("%w: %v", ErrorWhileReadingFile, err)
+		return err
+	}
+
+	return p.parce(name, data, target)
+}
+
+// ParceBytes разбирает data в target
+func (p *Parser) ParceBytes(data []byte, target interface{}) error {
+	return p.parce("", data, target)
+}
+
+// parce разбирает data, прочитанные из файла name, в target
+func (p *Parser) parce(name string, data []byte, target interface{}) error {
+	if len(p.errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrorInvalidOption, errors.Join(p.errs...))
+	}
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	if err := p.checkTags(reflect.TypeOf(target).Elem()); err != nil {
+		return fmt.Errorf("%w: %w", ErrorInvalidTag, err)
+	}
+
+	// без обработчика неизвестные ключи возвращаются в ошибке, но цель заполняется полностью
+	var unknown []error
+	warn := p.onUnknownField
+	if warn == nil {
+		warn = func(fe *FieldError) { unknown = append(unknown, fe) }
+	}
+	n, err := decodeJSON(name, data, target, p, warn)
+	if err != nil {
+		err := fmt.Errorf("%w: %w", ErrorWhileReadingFile, err)
+		return err
+	}
+	root := reflect.ValueOf(target).Elem()
+
+	var errs []error
+	w := p.newWalker(name, data, n)
+	w.walk(root, n, w.checkeRequiredFields)
+	if err := w.error(); err != nil {
+		err := fmt.Errorf("%w: %w", ErrorWhileChekingRequired, err)
+		if !p.allErrors {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	if err := w.invalidError(); err != nil {
+		err := fmt.Errorf("%w: %w", ErrorWhileValidating, err)
+		if !p.allErrors {
+			return err
+		}
+		errs = append(errs, err)
+	}
+
+	w = w.next()
+	w.walk(root, n, w.setDefaultFields)
+	if err := w.error(); err != nil {
+		err := fmt.Errorf("%w: %w", ErrorWhileUnmarshaling, err)
+		if !p.allErrors {
+			return err
+		}
+		errs = append(errs, err)
+	}
+
+	w = w.next()
+	w.walk(root, n, w.callValidators)
+	if err := w.error(); err != nil {
+		err := fmt.Errorf("%w: %w", ErrorWhileValidating, err)
+		if !p.allErrors {
+			return err
+		}
+		errs = append(errs, err)
+	}
+
+	for _, validate := range p.validators {
+		if err := validate(target); err != nil {
+			err := fmt.Errorf("%w: %w", ErrorWhileValidating, err)
+			if !p.allErrors {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	if len(unknown) > 0 {
+		warn := &unknownFieldsError{fmt.Errorf("%w: %w", ErrorUnknownFields, errors.Join(unknown...))}
+		if len(errs) == 0 {
+			return warn
+		}
+		errs = append(errs, warn)
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Parser) newWalker(name string, data []byte, root *node) *walker {
+	return &walker{p: p, file: name, data: data, root: root, keys: map[seenKey]mapKeys{}}
+}
+
+// next возвращает walker для следующего прохода по тому же документу
+func (w *walker) next() *walker {
+	return &walker{p: w.p, file: w.file, data: w.data, root: w.root, keys: w.keys}
+}
+
+// WithFieldTag задает тэг с именем ключа json и опцией required вместо "json"
+func WithFieldTag(name string) Option {
+	return func(p *Parser) {
+		p.fieldTag = name
+	}
+}
+
+// WithDefaultTag задает тэг со значением по умолчанию вместо "default"
+func WithDefaultTag(name string) Option {
+	return func(p *Parser) {
+		p.defaultTag = name
+	}
+}
+
+// WithRuleTag задает тэг с правилами полей вместо "parcer", например "validate".
+// Правила можно писать и в опциях тэга полей: json:"name,required"
+func WithRuleTag(name string) Option {
+	return func(p *Parser) {
+		p.ruleTag = name
+	}
+}
+
+// WithRule регистрирует правило name, которое можно указывать в тэгах наравне со встроенными.
+// Правило проверяет значения из json во всех вложенных структурах, срезах и отображениях.
+// Если name совпадает со встроенным правилом или опцией тэга json, например omitempty,
+// каждый разбор этим Parser возвращает ошибку ErrorInvalidOption
+func WithRule(name string, fn RuleFunc) Option {
+	return func(p *Parser) {
+		if err := checkRuleName(name); err != nil {
+			p.errs = append(p.errs, err)
+			return
+		}
+		if p.customRules == nil {
+			p.customRules = map[string]RuleFunc{}
+		}
+		p.customRules[name] = fn
+	}
+}
+
+// checkRuleName проверяет, что имя правила WithRule не занято и его можно записать в тэге
+func checkRuleName(name string) error {
+	if _, ok := ruleParams[name]; ok {
+		return fmt.Errorf("rule %q is built in", name)
+	}
+	if jsonOptions[name] {
+		return fmt.Errorf("rule %q is a json tag option", name)
+	}
+	if name == "" || strings.ContainsAny(name, ",= ") {
+		return fmt.Errorf("invalid rule name %q", name)
+	}
+	return nil
+}
+
+// WithDefaultSeparator задает разделитель элементов срезов и отображений
+// в значениях по умолчанию вместо ","
+func WithDefaultSeparator(sep string) Option {
+	return func(p *Parser) {
+		p.separator = sep
+	}
+}
+
+// WithAllocStructs создает пустые указатели на структуры, ключей которых нет в json,
+// и заполняет их значениями по умолчанию. Поле с тэгом default:"-" остается nil
+func WithAllocStructs() Option {
+	return func(p *Parser) {
+		p.allocStructs = true
+	}
+}
+
+// WithAllErrors собирает все отсутствующие обязательные поля и все некорректные значения
+// по умолчанию в одну ошибку вместо того, чтобы остановиться на первой.
+// Ошибки объединяются через errors.Join, поэтому errors.Is продолжает работать
+func WithAllErrors() Option {
+	return func(p *Parser) {
+		p.allErrors = true
+	}
+}
+
+// UnknownFieldPolicy определяет, что делать с ключами json, которым нет поля в цели
+type UnknownFieldPolicy int
+
+const (
+	RejectUnknownFields UnknownFieldPolicy = iota // вернуть ошибку разбора; по умолчанию
+	IgnoreUnknownFields                           // пропустить ключ
+	WarnUnknownFields                             // пропустить ключ и сообщить о нем, см. WithUnknownFields
+)
+
+// WithUnknownFields задает политику для неизвестных ключей.
+// В режиме WarnUnknownFields без WithUnknownFieldHandler цель заполняется полностью,
+// а неизвестные ключи возвращаются как *FieldError в ошибке ErrorUnknownFields
+func WithUnknownFields(policy UnknownFieldPolicy) Option {
+	return func(p *Parser) {
+		p.unknownFields = policy
+	}
+}
+
+// WithUnknownFieldHandler включает WarnUnknownFields и вызывает fn для каждого неизвестного ключа.
+// Field в ошибке — путь до структуры, в которой нет такого поля, Path — путь до самого ключа
+func WithUnknownFieldHandler(fn func(*FieldError)) Option {
+	return func(p *Parser) {
+		p.unknownFields = WarnUnknownFields
+		p.onUnknownField = fn
+	}
+}
+
+// WithValidator добавляет проверку, которая вызывается для всей цели после того,
+// как выставлены значения по умолчанию. Ошибка оборачивается в ErrorWhileValidating
+func WithValidator(fn func(target interface{}) error) Option {
+	return func(p *Parser) {
+		p.validators = append(p.validators, fn)
+	}
+}
+
+// WithDefaultProvider добавляет источник значений по умолчанию, например переменные окружения.
+// Источники опрашиваются по порядку до тэга default, первый ответивший побеждает
+func WithDefaultProvider(fn DefaultProvider) Option {
+	return func(p *Parser) {
+		p.defaults = append(p.defaults, fn)
+	}
+}
@@ -0,0 +1,302 @@
+package testparcer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+type testAllErrorsStruct struct {
+	Name    string                        `json:"name,required"`
+	Port    int                           `json:"port,required"`
+	Retries int                           `json:"retries" default:"many"`
+	Parent  testDefaultStruct             `json:"parent"`
+	Items   []*testDefaultStruct          `json:"items"`
+	Peers   map[string]*testDefaultStruct `json:"peers"`
+}
+
+func TestWithAllErrors(t *testing.T) {
+	data := []byte(`{"parent": {"age": 1}, "items": [{"age": 2}], "peers": {"a": {"age": 3}}}`)
+
+	err := ParceBytes(data, &testAllErrorsStruct{})
+	if !errors.Is(err, ErrorWhileChekingRequired) {
+		t.Fatalf("ParceBytes() error = %v, want %v", err, ErrorWhileChekingRequired)
+	}
+	if got := strings.Count(err.Error(), "is missing"); got != 1 {
+		t.Errorf("ParceBytes() reported %d missing fields, want 1: %v", got, err)
+	}
+
+	err = ParceBytes(data, &testAllErrorsStruct{}, WithAllErrors())
+	if !errors.Is(err, ErrorWhileChekingRequired) || !errors.Is(err, ErrorWhileUnmarshaling) {
+		t.Fatalf("ParceBytes() error = %v, want both %v and %v", err, ErrorWhileChekingRequired, ErrorWhileUnmarshaling)
+	}
+	var got []string
+	for _, fe := range FieldErrors(err) {
+		got = append(got, fe.Field)
+	}
+	want := []string{"Name", "Port", "Parent.Name", "Items[0].Name", "Peers[a].Name", "Retries"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FieldErrors() fields = %v, want %v", got, want)
+	}
+}
+
+func TestWithUnknownFields(t *testing.T) {
+	data := []byte(`{"name": "jin", "extra": 1, "parent": [{"name": "pa", "nested": {"x": 1}}]}`)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{"reject by default", nil, true},
+		{"reject", []Option{WithUnknownFields(RejectUnknownFields)}, true},
+		{"ignore", []Option{WithUnknownFields(IgnoreUnknownFields)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &testStructWithNestedArray{}
+			err := ParceBytes(data, target, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParceBytes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (target.Name != "jin" || len(target.Parent) != 1 || target.Parent[0].Age != 18) {
+				t.Errorf("ParceBytes() target = %+v", target)
+			}
+		})
+	}
+}
+
+func TestWithUnknownFields_warn(t *testing.T) {
+	data := []byte(`{"name": "jin", "extra": 1, "parent": [{"name": "pa", "nested": {"x": 1}}]}`)
+
+	target := &testStructWithNestedArray{}
+	err := ParceBytes(data, target, WithUnknownFields(WarnUnknownFields))
+	if !errors.Is(err, ErrorUnknownFields) {
+		t.Fatalf("ParceBytes() error = %v, want %v", err, ErrorUnknownFields)
+	}
+	if target.Name != "jin" || len(target.Parent) != 1 || target.Parent[0].Age != 18 {
+		t.Errorf("ParceBytes() target = %+v", target)
+	}
+
+	var got []string
+	for _, fe := range FieldErrors(err) {
+		got = append(got, string(fe.Kind)+" "+fe.Path)
+	}
+	want := []string{"unknown /extra", "unknown /parent/0/nested"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FieldErrors() = %q, want %q", got, want)
+	}
+}
+
+func TestWithUnknownFieldHandler(t *testing.T) {
+	data := []byte(`{"name": "jin", "extra": 1, "parent": [{"name": "pa", "nested": {"x": 1}}]}`)
+
+	var got []string
+	err := ParceBytes(data, &testStructWithNestedArray{}, WithUnknownFieldHandler(func(fe *FieldError) {
+		got = append(got, fe.Field+" "+fe.Path+" "+fe.Pos.String())
+	}))
+	if err != nil {
+		t.Fatalf("ParceBytes() error = %v", err)
+	}
+
+	want := []string{" /extra 1:17", "Parent[0] /parent/0/nested 1:55"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unknown fields = %q, want %q", got, want)
+	}
+}
+
+type testAllocDB struct {
+	Host string `json:"host" default:"localhost"`
+	Port int    `json:"port" default:"5432"`
+}
+
+type testAllocStruct struct {
+	DB      *testAllocDB `json:"db"`
+	Replica *testAllocDB `json:"replica" default:"-"`
+}
+
+func TestWithAllocStructs(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts []Option
+		want testAllocStruct
+	}{
+		{"disabled", `{}`, nil, testAllocStruct{}},
+		{"allocated", `{}`, []Option{WithAllocStructs()}, testAllocStruct{DB: &testAllocDB{"localhost", 5432}}},
+		{"present", `{"db": {"port": 1}}`, nil, testAllocStruct{DB: &testAllocDB{"localhost", 1}}},
+		{"explicit null", `{"db": null}`, []Option{WithAllocStructs()}, testAllocStruct{}},
+		{
+			"opt out present",
+			`{"replica": {}}`,
+			[]Option{WithAllocStructs()},
+			testAllocStruct{DB: &testAllocDB{"localhost", 5432}, Replica: &testAllocDB{"localhost", 5432}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testAllocStruct{}
+			if err := ParceBytes([]byte(tt.data), &got, tt.opts...); err != nil {
+				t.Fatalf("ParceBytes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParceBytes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testAllocNode struct {
+	Name string         `json:"name" default:"x"`
+	Next *testAllocNode `json:"next"`
+}
+
+func TestWithAllocStructs_recursive(t *testing.T) {
+	got := testAllocNode{}
+	if err := ParceBytes([]byte(`{}`), &got, WithAllocStructs()); err != nil {
+		t.Fatalf("ParceBytes() error = %v", err)
+	}
+	want := testAllocNode{Name: "x", Next: &testAllocNode{Name: "x"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParceBytes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParce_cyclicTarget(t *testing.T) {
+	got := &testAllocNode{}
+	got.Next = got
+	if err := ParceBytes([]byte(`{}`), got); err != nil {
+		t.Fatalf("ParceBytes() error = %v", err)
+	}
+	if got.Name != "x" || got.Next != got {
+		t.Errorf("ParceBytes() = %+v", got)
+	}
+}
+
+type testTagsStruct struct {
+	Name string `cfg:"name,required"`
+	Port int    `cfg:"port" def:"8080"`
+}
+
+func TestNewParser(t *testing.T) {
+	errInvalid := errors.New("invalid")
+
+	tests := []struct {
+		name    string
+		parser  *Parser
+		data    string
+		target  interface{}
+		want    interface{}
+		wantErr error
+	}{
+		{
+			"tag names",
+			NewParser(WithFieldTag("cfg"), WithDefaultTag("def")),
+			`{"name": "jin"}`,
+			&testTagsStruct{},
+			&testTagsStruct{Name: "jin", Port: 8080},
+			nil,
+		},
+		{
+			"tag names required",
+			NewParser(WithFieldTag("cfg"), WithDefaultTag("def")),
+			`{"port": 1}`,
+			&testTagsStruct{},
+			nil,
+			ErrorWhileChekingRequired,
+		},
+		{
+			"default provider",
+			NewParser(WithDefaultProvider(func(path string, field reflect.StructField) (string, bool) {
+				if path == "/age" {
+					return "42", true
+				}
+				return "", false
+			})),
+			`{"name": "jin"}`,
+			&testDefaultStruct{},
+			&testDefaultStruct{Name: "jin", Age: 42},
+			nil,
+		},
+		{
+			"validator",
+			NewParser(WithValidator(func(target interface{}) error {
+				if target.(*testDefaultStruct).Age < 21 {
+					return errInvalid
+				}
+				return nil
+			})),
+			`{"name": "jin"}`,
+			&testDefaultStruct{},
+			nil,
+			errInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parser.ParceBytes([]byte(tt.data), tt.target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParceBytes() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want != nil && !reflect.DeepEqual(tt.target, tt.want) {
+				t.Errorf("ParceBytes() target = %+v, want %+v", tt.target, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_concurrent(t *testing.T) {
+	p := NewParser(WithAllErrors(), WithUnknownFields(IgnoreUnknownFields))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				target := &testStruct{}
+				if err := p.ParceFS(testFS, "test3.json", target); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_readAll(t *testing.T) {
+	const data = `{"name": "jin"}`
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		name string
+		r    io.Reader
+	}{
+		{"len", strings.NewReader(data)},
+		{"file", f},
+		{"unknown size", iotest.OneByteReader(strings.NewReader(data))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAll(tt.r)
+			if err != nil || string(got) != data {
+				t.Errorf("readAll() = %q, %v, want %q", got, err, data)
+			}
+		})
+	}
+}
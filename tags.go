@@ -0,0 +1,261 @@
+package testparcer
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// rule правило поля из тэга: имя и параметр после "=", например min=1
+type rule struct {
+	name  string
+	param string
+	re    *regexp.Regexp // скомпилированный параметр pattern
+}
+
+// rules правила одного поля
+type rules []rule
+
+func (rs rules) get(name string) (rule, bool) {
+	for _, r := range rs {
+		if r.name == name {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+func (rs rules) has(name string) bool {
+	_, ok := rs.get(name)
+	return ok
+}
+
+// ruleParams известные правила и то, нужен ли им параметр
+var ruleParams = map[string]bool{
+	"required": false,
+	"min":      true,
+	"max":      true,
+	"len":      true,
+	"oneof":    true,
+	"pattern":  true,
+
+	"required_if":      true,
+	"required_with":    true,
+	"required_without": true,
+
+	"eqfield":  true,
+	"gtfield":  true,
+	"gtefield": true,
+	"ltfield":  true,
+	"ltefield": true,
+}
+
+// jsonOptions опции тэга json, которые обрабатывает encoding/json, а не Parser
+var jsonOptions = map[string]bool{
+	"omitempty": true,
+	"omitzero":  true,
+	"string":    true,
+}
+
+// typeRules правила полей структуры в порядке cachedFields
+type typeRules struct {
+	fields []rules
+	err    error
+}
+
+// cachedRules возвращает правила полей структуры t. Ошибка описывает первый некорректный тэг
+func (p *Parser) cachedRules(t reflect.Type) ([]rules, error) {
+	if tr, ok := p.rules.Load(t); ok {
+		return tr.(*typeRules).fields, tr.(*typeRules).err
+	}
+	tr := &typeRules{}
+	for _, f := range cachedFields(t, p.fieldTag).list {
+		sf := t.FieldByIndex(f.index)
+		rs, err := p.parseRules(sf)
+		if err == nil {
+			err = checkSiblings(rs, t, sf, cachedFields(t, p.fieldTag))
+		}
+		if err != nil && tr.err == nil {
+			tr.err = &FieldError{
+				Field: t.String() + "." + sf.Name,
+				Tag:   f.name,
+				Kind:  KindTag,
+				Err:   err,
+			}
+		}
+		tr.fields = append(tr.fields, rs)
+	}
+	v, _ := p.rules.LoadOrStore(t, tr)
+	return v.(*typeRules).fields, v.(*typeRules).err
+}
+
+// parseRules разбирает правила поля sf из опций тэга полей и из тэга правил
+func (p *Parser) parseRules(sf reflect.StructField) (rules, error) {
+	_, opts := parseJSONTag(sf.Tag.Get(p.fieldTag))
+	all := splitOptions(opts)
+	if p.ruleTag != p.fieldTag {
+		all = append(all, splitOptions(sf.Tag.Get(p.ruleTag))...)
+	}
+
+	t := sf.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var rs rules
+	for _, o := range all {
+		if jsonOptions[o] {
+			continue
+		}
+		r, err := p.parseRule(o)
+		if err != nil {
+			return nil, err
+		}
+		if r.name == "pattern" {
+			if err := p.checkPatternLast(r.param); err != nil {
+				return nil, err
+			}
+		}
+		if c, ok := constraints[r.name]; ok {
+			if err := c.prepare(t, &r); err != nil {
+				return nil, fmt.Errorf("option %q: %w", r.name, err)
+			}
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// siblingArgs возвращает ключи соседних полей, на которые ссылается правило r
+func siblingArgs(r rule) ([]string, error) {
+	args := strings.Fields(r.param)
+	switch r.name {
+	case "required_if":
+		if len(args) == 0 || len(args)%2 != 0 {
+			return nil, fmt.Errorf("option %q wants pairs of field and value", r.name)
+		}
+		var names []string
+		for i := 0; i < len(args); i += 2 {
+			names = append(names, args[i])
+		}
+		return names, nil
+	case "required_with", "required_without":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("option %q wants field names", r.name)
+		}
+		return args, nil
+	}
+	if _, ok := fieldComparisons[r.name]; ok && !strings.HasPrefix(r.param, "/") {
+		// путь от корня проверяется при обходе, так как корень заранее неизвестен
+		return []string{r.param}, nil
+	}
+	return nil, nil
+}
+
+// checkSiblings проверяет, что соседние поля из правил rs поля sf есть в структуре t
+// и что их можно сравнить с sf
+func checkSiblings(rs rules, t reflect.Type, sf reflect.StructField, fields *structFields) error {
+	for _, r := range rs {
+		names, err := siblingArgs(r)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			i, ok := fields.byName[name]
+			if !ok {
+				return fmt.Errorf("option %q: unknown field %q", r.name, name)
+			}
+			if _, ok := fieldComparisons[r.name]; ok {
+				if err := checkComparison(r, sf.Type, t.FieldByIndex(fields.list[i].index).Type); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseRule разбирает одну опцию тэга и проверяет, что такое правило есть.
+// Параметр правил WithRule необязателен
+func (p *Parser) parseRule(opt string) (rule, error) {
+	name, param, hasParam := strings.Cut(opt, "=")
+	if _, ok := p.customRules[name]; ok {
+		return rule{name: name, param: param}, nil
+	}
+	needParam, ok := ruleParams[name]
+	switch {
+	case !ok:
+		return rule{}, fmt.Errorf("unknown option %q", opt)
+	case needParam && !hasParam:
+		return rule{}, fmt.Errorf("option %q requires a parameter", name)
+	case !needParam && hasParam:
+		return rule{}, fmt.Errorf("option %q takes no parameter", name)
+	}
+	return rule{name: name, param: param}, nil
+}
+
+// checkPatternLast проверяет, что за pattern не идут другие опции: pattern забирает
+// остаток тэга, и опция после него молча стала бы частью выражения
+func (p *Parser) checkPatternLast(param string) error {
+	_, rest, _ := strings.Cut(param, ",")
+	for _, o := range strings.Split(rest, ",") {
+		o = strings.TrimSpace(o)
+		name, _, _ := strings.Cut(o, "=")
+		_, builtIn := ruleParams[name]
+		_, custom := p.customRules[name]
+		if builtIn || custom || jsonOptions[o] {
+			return fmt.Errorf("option \"pattern\" must be the last option, found %q after it", o)
+		}
+	}
+	return nil
+}
+
+// splitOptions делит опции тэга по запятым, пропуская пустые.
+// Параметр pattern может содержать запятые, поэтому pattern забирает остаток тэга
+// и должен быть последней опцией
+func splitOptions(opts string) []string {
+	var all []string
+	for opts != "" {
+		var o string
+		if strings.HasPrefix(strings.TrimSpace(opts), "pattern=") {
+			o, opts = opts, ""
+		} else {
+			o, opts, _ = strings.Cut(opts, ",")
+		}
+		if o = strings.TrimSpace(o); o != "" {
+			all = append(all, o)
+		}
+	}
+	return all
+}
+
+// checkTags проверяет тэги всех структур, достижимых из типа t
+func (p *Parser) checkTags(t reflect.Type) error {
+	if err, ok := p.checked.Load(t); ok {
+		err, _ := err.(error)
+		return err
+	}
+	err := p.checkTypeTags(t, map[reflect.Type]bool{})
+	p.checked.Store(t, err)
+	return err
+}
+
+func (p *Parser) checkTypeTags(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] || isUnmarshaler(t) {
+		return nil
+	}
+	seen[t] = true
+
+	if _, err := p.cachedRules(t); err != nil {
+		return err
+	}
+	for _, f := range cachedFields(t, p.fieldTag).list {
+		if err := p.checkTypeTags(t.FieldByIndex(f.index).Type, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package testparcer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_parseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     string
+		want    rule
+		wantErr bool
+	}{
+		{"required", "required", rule{name: "required"}, false},
+		{"unknown", "requird", rule{}, true},
+		{"substring", "notrequired", rule{}, true},
+		{"unexpected param", "required=true", rule{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := defaultParser.parseRule(tt.opt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type testRulesStruct struct {
+	RequiredBy string `json:"required_by"`
+	Name       string `json:"name,omitempty" parcer:"required"`
+	Port       int    `json:"port,required"`
+	Host       string `json:"host" validate:"required"`
+}
+
+type testRulesNested struct {
+	Inner *struct {
+		Name string `json:"name,requred"`
+	} `json:"inner"`
+}
+
+func TestParser_rules(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		data    string
+		target  interface{}
+		wantErr error
+	}{
+		{"json and rule tag", nil, `{"name": "a", "port": 1}`, &testRulesStruct{}, nil},
+		{"key named required", nil, `{"name": "a", "port": 1}`, &testRulesStruct{}, nil},
+		{"rule tag", nil, `{"port": 1}`, &testRulesStruct{}, ErrorWhileChekingRequired},
+		{"custom rule tag", []Option{WithRuleTag("validate")}, `{"port": 1}`, &testRulesStruct{}, ErrorWhileChekingRequired},
+		{"custom rule tag set", []Option{WithRuleTag("validate")}, `{"port": 1, "host": "h"}`, &testRulesStruct{}, nil},
+		{"unknown option", nil, `{}`, &testRulesNested{}, ErrorInvalidTag},
+		{
+			"unknown option in rule tag",
+			nil,
+			`{}`,
+			&struct {
+				Name string `parcer:"required,sometimes"`
+			}{},
+			ErrorInvalidTag,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), tt.target, tt.opts...)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("ParceBytes() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParser_rulesError(t *testing.T) {
+	err := ParceBytes([]byte(`{}`), &testRulesNested{})
+
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("ParceBytes() error = %v, want *FieldError", err)
+	}
+	if fe.Kind != KindTag || fe.Tag != "name" {
+		t.Errorf("ParceBytes() error = %+v", *fe)
+	}
+}
+
+func Test_splitOptions(t *testing.T) {
+	tests := []struct {
+		opts string
+		want []string
+	}{
+		{"required, min=1,,oneof=a b", []string{"required", "min=1", "oneof=a b"}},
+		{"required,pattern=^a{1,3}$", []string{"required", "pattern=^a{1,3}$"}},
+	}
+	for _, tt := range tests {
+		if got := splitOptions(tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitOptions(%q) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
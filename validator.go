@@ -0,0 +1,100 @@
+package testparcer
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Validator реализуют значения, которые проверяют себя сами. Parce вызывает Validate у цели
+// и у всех вложенных значений после того, как выставлены значения по умолчанию:
+// сначала у вложенных, затем у содержащих их
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+type seenKey struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// callValidators обходит значение v, записанное в n, и вызывает Validate
+func (w *walker) callValidators(v reflect.Value, n *node) {
+	if w.done() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if w.visit(v) {
+			w.callValidators(v.Elem(), n)
+		}
+		return
+	case reflect.Interface:
+		if !v.IsNil() {
+			w.callValidators(v.Elem(), n)
+		}
+		return
+	case reflect.Struct:
+		fields := cachedFields(v.Type(), w.p.fieldTag)
+		keys, mark := w.fieldKeys(n, fields)
+		defer w.dropKeys(mark)
+		for i := 0; i < len(fields.list) && !w.done(); i++ {
+			f, ok := fieldValue(v, fields.list[i].index)
+			if !ok {
+				continue
+			}
+			w.enter(step{field: fields.list[i].goName, token: keys[i], at: n.field(keys[i])})
+			w.callValidators(f, n.field(keys[i]))
+			w.leave()
+			w.compareFields(v, n, i, f, fields, keys)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len() && !w.done(); i++ {
+			key := strconv.Itoa(i)
+			w.enter(step{field: "[" + key + "]", token: key, at: n.elem(i)})
+			w.callValidators(v.Index(i), n.elem(i))
+			w.leave()
+		}
+	case reflect.Map:
+		for _, key := range w.sortedKeys(v) {
+			if w.done() {
+				break
+			}
+			k := mapKey(key)
+			w.enter(step{field: "[" + k + "]", token: k, at: n.field(k)})
+			w.callValidators(v.MapIndex(key), n.field(k))
+			w.leave()
+		}
+	}
+
+	if w.done() {
+		return
+	}
+	if err := validateValue(v); err != nil {
+		field, path := stepPaths(w.path)
+		fe := &FieldError{Field: field, Path: path, Kind: KindValidate, Err: err}
+		if len(w.path) > 0 {
+			fe.Tag = w.path[len(w.path)-1].token
+		}
+		if w.data != nil {
+			fe.Pos = position(w.file, w.data, w.nearest(n).start)
+		}
+		w.fail(fe)
+	}
+}
+
+// validateValue вызывает Validate у v или у его адреса, если метод объявлен у указателя
+func validateValue(v reflect.Value) error {
+	if v.Type().Implements(validatorType) {
+		return v.Interface().(Validator).Validate()
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(validatorType) {
+		return v.Addr().Interface().(Validator).Validate()
+	}
+	return nil
+}
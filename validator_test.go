@@ -0,0 +1,117 @@
+package testparcer
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var errTestValidate = errors.New("invalid")
+
+// testValidateCalls запоминает порядок вызовов Validate
+var testValidateCalls []string
+
+type testValidateLeaf struct {
+	Name string `json:"name"`
+	Port int    `json:"port" default:"80"`
+}
+
+func (l *testValidateLeaf) Validate() error {
+	testValidateCalls = append(testValidateCalls, "leaf "+l.Name)
+	if l.Port == 0 {
+		return fmt.Errorf("port of %q is not set", l.Name)
+	}
+	if l.Name == "bad" {
+		return errTestValidate
+	}
+	return nil
+}
+
+type testValidateMode string
+
+func (m testValidateMode) Validate() error {
+	testValidateCalls = append(testValidateCalls, "mode "+string(m))
+	if m != "" && m != "dev" && m != "prod" {
+		return errTestValidate
+	}
+	return nil
+}
+
+type testValidateRoot struct {
+	Mode   testValidateMode             `json:"mode"`
+	Leaf   testValidateLeaf             `json:"leaf"`
+	Ptr    *testValidateLeaf            `json:"ptr"`
+	Leaves []testValidateLeaf           `json:"leaves"`
+	ByName map[string]*testValidateLeaf `json:"by-name"`
+	Values map[string]testValidateMode  `json:"values"`
+	Self   *testValidateRoot            `json:"-"`
+}
+
+func (r *testValidateRoot) Validate() error {
+	testValidateCalls = append(testValidateCalls, "root")
+	if r.Leaf.Name == r.Mode.String() {
+		return errTestValidate
+	}
+	return nil
+}
+
+func (m testValidateMode) String() string {
+	return string(m)
+}
+
+func TestValidator(t *testing.T) {
+	data := `{"mode": "dev", "leaf": {"name": "a"}, "ptr": {"name": "b"}, "leaves": [{"name": "c"}],
+		"by-name": {"d": {"name": "d"}}, "values": {"e": "prod"}}`
+	target := &testValidateRoot{}
+	target.Self = target
+
+	testValidateCalls = nil
+	if err := ParceBytes([]byte(data), target); err != nil {
+		t.Fatalf("ParceBytes() error = %v", err)
+	}
+	want := []string{"mode dev", "leaf a", "leaf b", "leaf c", "leaf d", "mode prod", "root"}
+	if !reflect.DeepEqual(testValidateCalls, want) {
+		t.Errorf("Validate() calls = %q, want %q", testValidateCalls, want)
+	}
+}
+
+func TestValidator_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		field string
+		path  string
+		pos   string
+	}{
+		{"root", `{"mode": "dev", "leaf": {"name": "dev"}}`, "", "", "1:1"},
+		{"string type", `{"mode": "test"}`, "Mode", "/mode", "1:10"},
+		{"slice element", `{"leaves": [{"name": "ok"}, {"name": "bad"}]}`, "Leaves[1]", "/leaves/1", "1:29"},
+		{"map value", `{"by-name": {"x": {"name": "bad"}}}`, "ByName[x]", "/by-name/x", "1:19"},
+		{"pointer", `{"ptr": {"name": "bad", "port": 1}}`, "Ptr", "/ptr", "1:9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParceBytes([]byte(tt.data), &testValidateRoot{})
+			if !errors.Is(err, ErrorWhileValidating) || !errors.Is(err, errTestValidate) {
+				t.Fatalf("ParceBytes() error = %v", err)
+			}
+
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				t.Fatalf("ParceBytes() error = %v, want *FieldError", err)
+			}
+			if fe.Kind != KindValidate || fe.Field != tt.field || fe.Path != tt.path || fe.Pos.String() != tt.pos {
+				t.Errorf("ParceBytes() error = %+v", *fe)
+			}
+		})
+	}
+}
+
+func TestValidator_defaultsApplied(t *testing.T) {
+	// Port получает значение по умолчанию до вызова Validate
+	err := ParceBytes([]byte(`{"leaf": {"name": "x"}}`), &testValidateRoot{})
+	if err != nil {
+		t.Errorf("ParceBytes() error = %v", err)
+	}
+}